@@ -3,20 +3,27 @@ package main
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	// Часовые пояса расписаний не зависят от образа
+	_ "time/tzdata"
+
 	"github.com/korikhin/pod-sync/internal/config"
 	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
 	"github.com/korikhin/pod-sync/internal/server/handlers"
 	"github.com/korikhin/pod-sync/internal/server/middleware/logger"
 	"github.com/korikhin/pod-sync/internal/server/middleware/request"
 	storage "github.com/korikhin/pod-sync/internal/storage/postgres"
 	"github.com/korikhin/pod-sync/internal/watcher"
 
-	deployer "github.com/korikhin/pod-sync/pkg/deployer/mocks"
+	"github.com/korikhin/pod-sync/pkg/deployer"
+	"github.com/korikhin/pod-sync/pkg/deployer/breaker"
+	"github.com/korikhin/pod-sync/pkg/deployer/mocks"
 )
 
 func main() {
@@ -25,17 +32,44 @@ func main() {
 	log := sl.New()
 	log.Debug("debug messages are enabled")
 
+	// Правило именования подов
+	namer, err := models.NewPodNamer(cfg.Pods.NameTemplate, cfg.Pods.Env)
+	if err != nil {
+		log.Error("invalid pod name template", sl.Error(err))
+		os.Exit(1)
+	}
+
+	// Ресурсы кластера
+	capacity, err := models.ParseCapacity(cfg.Capacity.CPU, cfg.Capacity.Memory, cfg.Capacity.Preemption)
+	if err != nil {
+		log.Error("invalid cluster capacity", sl.Error(err))
+		os.Exit(1)
+	}
+
 	// Конфигурация хранилища
-	storage, err := storage.New(context.Background(), cfg.Storage)
+	storage, err := storage.New(context.Background(), log, cfg.Storage, namer, capacity)
 	if err != nil {
 		log.Error("failed to initialize the storage", sl.Error(err))
 		os.Exit(1)
 	}
 
-	deployer := deployer.New()
+	// Размыкатель вызовов Deployer'а
+	var d deployer.Deployer = mocks.New()
+	var b *breaker.Breaker
+	if cfg.Deployer.BreakerThreshold > 0 {
+		b = breaker.New(d, cfg.Deployer.BreakerThreshold, cfg.Deployer.BreakerCooldown,
+			func(from, to breaker.State) {
+				log.Warn("deployer circuit breaker state changed",
+					slog.String("from", string(from)),
+					slog.String("to", string(to)),
+				)
+			},
+		)
+		d = b
+	}
 
 	// Сервис реализующий синхронизацию статусов
-	watcher := watcher.New(log, deployer, cfg.Sync)
+	watcher := watcher.New(log, storage, d, namer, cfg.Sync)
 	watcher.Start()
 
 	// Конфигурация HTTP сервера
@@ -44,7 +78,7 @@ func main() {
 		request.ID(),
 		logger.New(log),
 	)
-	handlers.RegisterHandlers(router, log, storage, watcher)
+	handlers.RegisterHandlers(router, log, storage, watcher, b)
 	server := &http.Server{
 		Addr:           ":8080",
 		Handler:        router,
@@ -87,7 +121,15 @@ func main() {
 		log.Error("error occurred while stopping the server", sl.Error(err))
 	}
 
-	watcher.Stop() // Ожидаем остановку
+	// Остановка Watcher'а после сервера, чтобы не принимать новые операции
+	wCtx, wCancel := context.WithTimeout(context.Background(), cfg.Sync.ShutdownTimeout)
+	defer wCancel()
+
+	if err := watcher.Stop(wCtx); err != nil {
+		log.Error("error occurred while stopping the watcher", sl.Error(err))
+	}
+
+	// Если Watcher не остановился вовремя, соединение лидера уже закрыто
 	storage.Stop() // Ожидаем закрытия всех соединений
 
 	log.Info("service stopped")
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"strings"
 	"time"
@@ -11,13 +12,81 @@ import (
 )
 
 type Config struct {
-	Sync    `koanf:"sync"`
-	Storage `koanf:"storage"`
-	HTTP    `koanf:"http"`
+	Sync     `koanf:"sync"`
+	Pods     `koanf:"pods"`
+	Capacity `koanf:"capacity"`
+	Deployer `koanf:"deployer"`
+	Storage  `koanf:"storage"`
+	HTTP     `koanf:"http"`
 }
 
 type Sync struct {
-	Interval time.Duration `koanf:"interval"`
+	Interval    time.Duration `koanf:"interval"`
+	Workers     int           `koanf:"workers"`
+	BatchSize   int           `koanf:"batch-size"`
+	MaxAttempts int           `koanf:"max-attempts"`
+	BackoffBase time.Duration `koanf:"backoff-base"`
+	BackoffMax  time.Duration `koanf:"backoff-max"`
+
+	ShutdownPolicy  string        `koanf:"shutdown-policy"`
+	ShutdownTimeout time.Duration `koanf:"shutdown-timeout"`
+
+	ElectionInterval time.Duration `koanf:"election-interval"`
+	ScheduleInterval time.Duration `koanf:"schedule-interval"`
+	ClaimTimeout     time.Duration `koanf:"claim-timeout"`
+
+	GC      `koanf:"gc"`
+	Restart `koanf:"restart"`
+	TTL     `koanf:"ttl"`
+}
+
+// GC содержит параметры удаления подов, оставшихся от удалённых клиентов.
+type GC struct {
+	Enabled      bool          `koanf:"enabled"`
+	GracePeriod  time.Duration `koanf:"grace-period"`
+	MaxDeletions int           `koanf:"max-deletions"`
+	Allowlist    []string      `koanf:"allowlist"`
+}
+
+// TTL содержит время жизни включённых подов по типам; 0 — без ограничений.
+type TTL struct {
+	X time.Duration `koanf:"x"`
+	Y time.Duration `koanf:"y"`
+	Z time.Duration `koanf:"z"`
+}
+
+// Pods содержит параметры именования подов.
+// Пустой шаблон соответствует прежнему правилу <тип>-<идентификатор статуса>.
+type Pods struct {
+	NameTemplate string `koanf:"name-template"`
+	Env          string `koanf:"env"`
+}
+
+// Capacity содержит ресурсы кластера, доступные подам, в формате Kubernetes
+// (500m, 2, 512Mi, 1Gi), и политику вытеснения подов при их нехватке.
+// Пустое значение снимает ограничение по ресурсу.
+type Capacity struct {
+	CPU        string `koanf:"cpu"`
+	Memory     string `koanf:"mem"`
+	Preemption string `koanf:"preemption"`
+}
+
+// Restart содержит параметры перезапуска подов.
+// Ограничения недоступности действуют только при последовательном перезапуске;
+// 0 — без ограничений.
+type Restart struct {
+	Rolling                 bool          `koanf:"rolling"`
+	MaxUnavailable          int           `koanf:"max-unavailable"`
+	MaxUnavailablePerClient int           `koanf:"max-unavailable-per-client"`
+	ReadyTimeout            time.Duration `koanf:"ready-timeout"`
+	ReadyPollInterval       time.Duration `koanf:"ready-poll-interval"`
+}
+
+// Deployer содержит параметры размыкателя вызовов Deployer'а.
+// Размыкатель отключён, если BreakerThreshold равен 0.
+type Deployer struct {
+	BreakerThreshold int           `koanf:"breaker-threshold"`
+	BreakerCooldown  time.Duration `koanf:"breaker-cooldown"`
 }
 
 type Storage struct {
@@ -41,7 +110,40 @@ type HTTP struct {
 func defaultConfig() *Config {
 	return &Config{
 		Sync: Sync{
-			Interval: 300 * time.Second,
+			Interval:    300 * time.Second,
+			Workers:     4,
+			BatchSize:   100,
+			MaxAttempts: 5,
+			BackoffBase: 10 * time.Second,
+			BackoffMax:  30 * time.Minute,
+
+			ShutdownPolicy:  "drain",
+			ShutdownTimeout: 30 * time.Second,
+
+			ElectionInterval: 10 * time.Second,
+			ScheduleInterval: 30 * time.Second,
+			ClaimTimeout:     15 * time.Minute,
+
+			GC: GC{
+				Enabled:      false,
+				GracePeriod:  15 * time.Minute,
+				MaxDeletions: 10,
+			},
+
+			Restart: Restart{
+				Rolling:                 false,
+				MaxUnavailable:          1,
+				MaxUnavailablePerClient: 1,
+				ReadyTimeout:            2 * time.Minute,
+				ReadyPollInterval:       2 * time.Second,
+			},
+		},
+		Capacity: Capacity{
+			Preemption: "never",
+		},
+		Deployer: Deployer{
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
 		},
 		Storage: Storage{
 			MinConns:       1,
@@ -94,5 +196,30 @@ func MustLoad() *Config {
 		log.Fatalf("error: %v", err)
 	}
 
+	if err := cfg.validate(); err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
 	return cfg
 }
+
+// validate проверяет значения параметров, недопустимые для сервиса.
+func (cfg *Config) validate() error {
+	// Интервалы задают периоды таймеров и должны быть положительными
+	intervals := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"sync.interval", cfg.Sync.Interval},
+		{"sync.election-interval", cfg.Sync.ElectionInterval},
+		{"sync.schedule-interval", cfg.Sync.ScheduleInterval},
+		{"sync.claim-timeout", cfg.Sync.ClaimTimeout},
+		{"sync.restart.ready-poll-interval", cfg.Sync.Restart.ReadyPollInterval},
+	}
+	for _, i := range intervals {
+		if i.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %v", i.name, i.value)
+		}
+	}
+	return nil
+}
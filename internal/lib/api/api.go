@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/quantity"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -19,11 +22,22 @@ var (
 	ErrBadRequest     = Error("bad request")
 	ErrClientNotFound = Error("no such client")
 	ErrStatusNotFound = Error("no such status")
+	ErrInvalidPodName = Error("client name does not produce a valid pod name")
+
+	ErrDeadLetterNotFound = Error("no such dead letter")
+	ErrOperationNotFound  = Error("no such operation")
+	ErrScheduleNotFound   = Error("no such schedule")
+	ErrRolloutNotFound    = Error("no such rollout")
+	ErrRolloutRunning     = Error("another rollout is running")
+	ErrRolloutNotRunning  = Error("rollout is not running")
+	ErrNotLeader          = Error("sync is not running on this instance")
+	ErrPaused             = Error("sync is paused")
 )
 
 type Response struct {
-	Status  string `json:"status"`
-	Message string `json:"message,omitempty"`
+	Status  string      `json:"status"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
 func OK(m string) Response {
@@ -33,6 +47,13 @@ func OK(m string) Response {
 	}
 }
 
+func Data(v interface{}) Response {
+	return Response{
+		Status: statusOK,
+		Data:   v,
+	}
+}
+
 func Error(m string) Response {
 	return Response{
 		Status:  statusError,
@@ -44,9 +65,12 @@ type Client struct {
 	Name     *string  `json:"name" validate:"required"`
 	Version  *int     `json:"version" validate:"required"`
 	Image    *string  `json:"image" validate:"required"`
-	CPU      *string  `json:"cpu" validate:"required"`
-	Memory   *string  `json:"mem" validate:"required"`
+	CPU      *string  `json:"cpu" validate:"required,cpu"`
+	Memory   *string  `json:"mem" validate:"required,mem"`
 	Priority *float64 `json:"priority" validate:"required"`
+
+	// Стратегия перезапуска подов, по умолчанию recreate
+	RestartStrategy *string `json:"restart_strategy" validate:"omitempty,oneof=recreate surge"`
 }
 
 type Status struct {
@@ -55,6 +79,177 @@ type Status struct {
 	Z *bool `json:"Z" validate:"required"`
 }
 
+type Renew struct {
+	Types []string `json:"pods" validate:"omitempty,dive,oneof=X Y Z"`
+}
+
+type StatusTTL struct {
+	TTL *string `json:"ttl"`
+}
+
+type RestartFilter struct {
+	Image     *string  `json:"image"`
+	Version   *int     `json:"version"`
+	ClientIDs []int    `json:"client_ids" validate:"omitempty,dive,gt=0"`
+	Types     []string `json:"types" validate:"omitempty,dive,oneof=X Y Z"`
+}
+
+// Schedule задаёт либо разовое изменение (At, On),
+// либо повторяющееся окно (Cron, Duration, Timezone).
+type Schedule struct {
+	Types    []string   `json:"pods" validate:"required,min=1,dive,oneof=X Y Z"`
+	At       *time.Time `json:"at" validate:"required_without=Cron,excluded_with=Cron"`
+	On       *bool      `json:"on" validate:"required_with=At,excluded_with=Cron"`
+	Cron     *string    `json:"cron" validate:"required_without=At"`
+	Duration *string    `json:"duration" validate:"required_with=Cron,excluded_with=At"`
+	Timezone *string    `json:"timezone" validate:"excluded_with=At"`
+}
+
+type ScheduleInfo struct {
+	ID        int        `json:"id"`
+	StatusID  int        `json:"status_id"`
+	Types     []string   `json:"pods"`
+	At        *time.Time `json:"at,omitempty"`
+	On        *bool      `json:"on,omitempty"`
+	Cron      string     `json:"cron,omitempty"`
+	Duration  string     `json:"duration,omitempty"`
+	Timezone  string     `json:"timezone,omitempty"`
+	LastOn    *bool      `json:"last_on,omitempty"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Rollout задаёт поэтапный выкат образа и версии на клиентов,
+// соответствующих Selector.
+type Rollout struct {
+	Image          *string          `json:"image" validate:"required"`
+	Version        *int             `json:"version" validate:"required"`
+	Selector       *RolloutSelector `json:"selector" validate:"required"`
+	BatchSize      *int             `json:"batch_size" validate:"required,gt=0"`
+	Pause          *string          `json:"pause"`
+	MaxFailureRate *float64         `json:"max_failure_rate" validate:"omitempty,gte=0,lte=1"`
+}
+
+type RolloutSelector struct {
+	Image     *string `json:"image"`
+	Version   *int    `json:"version"`
+	ClientIDs []int   `json:"client_ids" validate:"omitempty,dive,gt=0"`
+}
+
+type RolloutInfo struct {
+	ID             int64           `json:"id"`
+	Image          string          `json:"image"`
+	Version        int             `json:"version"`
+	Selector       RolloutSelector `json:"selector"`
+	BatchSize      int             `json:"batch_size"`
+	Pause          string          `json:"pause"`
+	MaxFailureRate float64         `json:"max_failure_rate"`
+	State          string          `json:"state"`
+	Reason         string          `json:"reason,omitempty"`
+	Clients        int             `json:"clients"`
+	Updated        int             `json:"updated"`
+	Wave           *RolloutWave    `json:"wave,omitempty"`
+	NextWaveAt     *time.Time      `json:"next_wave_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+type RolloutWave struct {
+	Number     int        `json:"number"`
+	RequestID  string     `json:"request_id"`
+	Operations int        `json:"operations"`
+	Failed     int        `json:"failed"`
+	Pending    int        `json:"pending"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+}
+
+type DeadLetter struct {
+	ID        int64     `json:"id"`
+	PodID     string    `json:"pod_id"`
+	Operation string    `json:"operation"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+type OperationResult struct {
+	PodID     string `json:"pod_id"`
+	Operation string `json:"operation"`
+	Outcome   string `json:"outcome"`
+	Error     string `json:"error,omitempty"`
+}
+
+type Operation struct {
+	ID            int64  `json:"id"`
+	PodID         string `json:"pod_id"`
+	PreviousPodID string `json:"previous_pod_id,omitempty"`
+	Operation     string `json:"operation"`
+}
+
+type OperationStatus struct {
+	ID            int64     `json:"id"`
+	PodID         string    `json:"pod_id"`
+	PreviousPodID string    `json:"previous_pod_id,omitempty"`
+	Operation     string    `json:"operation"`
+	State         string    `json:"state"`
+	Attempts      int       `json:"attempts"`
+	Error         string    `json:"error,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type OperationRecord struct {
+	ID            int64     `json:"id"`
+	OperationID   int64     `json:"operation_id,omitempty"`
+	PodID         string    `json:"pod_id"`
+	PreviousPodID string    `json:"previous_pod_id,omitempty"`
+	Operation     string    `json:"operation"`
+	ClientID      int       `json:"client_id,omitempty"`
+	RequestID     string    `json:"request_id,omitempty"`
+	Attempt       int       `json:"attempt"`
+	Result        string    `json:"result"`
+	Error         string    `json:"error,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+}
+
+type Preemption struct {
+	ID                  int64     `json:"id"`
+	StatusID            int       `json:"status_id"`
+	ClientID            int       `json:"client_id"`
+	Pods                []string  `json:"pods"`
+	Priority            float64   `json:"priority"`
+	PreemptedByStatusID int       `json:"preempted_by_status_id"`
+	PreemptedByClientID int       `json:"preempted_by_client_id"`
+	PreemptedByPriority float64   `json:"preempted_by_priority"`
+	RequestID           string    `json:"request_id,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+type Drift struct {
+	Missing    []string `json:"missing"`
+	Unexpected []string `json:"unexpected"`
+	Stale      []string `json:"stale"`
+	Orphans    []string `json:"orphans"`
+}
+
+type Health struct {
+	Watcher  WatcherStats  `json:"watcher"`
+	Deployer DeployerStats `json:"deployer"`
+}
+
+type DeployerStats struct {
+	Breaker         string `json:"breaker"`
+	BreakerFailures int    `json:"breaker_failures"`
+	BreakerOpened   uint64 `json:"breaker_opened"`
+	BreakerRejected uint64 `json:"breaker_rejected"`
+}
+
+type WatcherStats struct {
+	Leader              bool   `json:"leader"`
+	Paused              bool   `json:"paused"`
+	OperationsCollapsed uint64 `json:"operations_collapsed"`
+}
+
 func NewValidator(opts ...validator.Option) *validator.Validate {
 	v := validator.New(opts...)
 	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -64,6 +259,14 @@ func NewValidator(opts ...validator.Option) *validator.Validate {
 		}
 		return name
 	})
+	v.RegisterValidation("cpu", func(fl validator.FieldLevel) bool {
+		_, err := quantity.ParseCPU(fl.Field().String())
+		return err == nil
+	})
+	v.RegisterValidation("mem", func(fl validator.FieldLevel) bool {
+		_, err := quantity.ParseMemory(fl.Field().String())
+		return err == nil
+	})
 	return v
 }
 
@@ -84,7 +287,7 @@ func formatErrors(errs validator.ValidationErrors) error {
 	}
 	var msg string
 	switch e := errs[0]; e.Tag() {
-	case "required":
+	case "required", "required_with", "required_without":
 		msg = fmt.Sprintf("field %s is required", e.Field())
 	default:
 		msg = fmt.Sprintf("field %s is not valid", e.Field())
@@ -0,0 +1,257 @@
+// Пакет cron разбирает выражения cron из пяти полей:
+// минуты, часы, день месяца, месяц, день недели.
+//
+// Поддерживаются значения, списки (1,3,5), диапазоны (1-5), шаги (*/15, 1-30/5)
+// и символ *. День недели задаётся числом от 0 до 7, где 0 и 7 — воскресенье.
+// Если ограничены и день месяца, и день недели, выражение совпадает
+// с любым из них, как в классическом cron. Поле, включающее все допустимые
+// значения (*, */1, 1-31), не считается ограниченным.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// Expression описывает разобранное выражение cron.
+type Expression struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// Ограничены ли день месяца и день недели
+	domRestricted bool
+	dowRestricted bool
+}
+
+type bounds struct {
+	min, max int
+}
+
+var (
+	minuteBounds = bounds{0, 59}
+	hourBounds   = bounds{0, 23}
+	domBounds    = bounds{1, 31}
+	monthBounds  = bounds{1, 12}
+	dowBounds    = bounds{0, 7}
+)
+
+// Parse разбирает выражение cron.
+func Parse(expr string) (*Expression, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d", ErrInvalidExpression, len(fields))
+	}
+
+	e := &Expression{}
+	var err error
+
+	if e.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if e.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if e.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if e.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if e.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+
+	// Воскресенье может быть задано как 7
+	if e.dow&(1<<7) != 0 {
+		e.dow |= 1
+	}
+
+	e.domRestricted = e.dom != fullMask(domBounds)
+	e.dowRestricted = e.dow&fullMask(bounds{0, 6}) != fullMask(bounds{0, 6})
+
+	return e, nil
+}
+
+// parseField возвращает битовую маску значений поля.
+func parseField(field string, b bounds) (uint64, error) {
+	var mask uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%w: bad step %q", ErrInvalidExpression, part)
+			}
+			step = n
+		}
+
+		lo, hi := b.min, b.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			loText, hiText, _ := strings.Cut(rng, "-")
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(loText)
+			hi, err2 = strconv.Atoi(hiText)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("%w: bad range %q", ErrInvalidExpression, part)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("%w: bad value %q", ErrInvalidExpression, part)
+			}
+			lo = n
+			if !hasStep {
+				hi = n
+			}
+		}
+
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("%w: %q out of range %d-%d", ErrInvalidExpression, part, b.min, b.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << v
+		}
+	}
+
+	return mask, nil
+}
+
+// fullMask возвращает битовую маску всех значений поля.
+func fullMask(b bounds) uint64 {
+	return (1<<(b.max+1) - 1) &^ (1<<b.min - 1)
+}
+
+// Match сообщает, совпадает ли минута t с выражением.
+// Время сравнивается в часовом поясе t.
+func (e *Expression) Match(t time.Time) bool {
+	if e.minute&(1<<t.Minute()) == 0 ||
+		e.hour&(1<<t.Hour()) == 0 ||
+		e.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+
+	return e.matchDay(t)
+}
+
+// matchDay сообщает, совпадает ли день t с выражением.
+func (e *Expression) matchDay(t time.Time) bool {
+	domMatch := e.dom&(1<<t.Day()) != 0
+	dowMatch := e.dow&(1<<int(t.Weekday())) != 0
+
+	if e.domRestricted && e.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// Prev возвращает последнее совпадение с выражением не позднее t,
+// но не ранее t - limit. Возвращает false, если совпадений нет.
+//
+// Несовпадающие месяц, день и час пропускаются целиком. Переходы
+// на летнее время учитываются: несуществующие минуты не возвращаются,
+// а повторяющиеся проверяются дважды.
+func (e *Expression) Prev(t time.Time, limit time.Duration) (time.Time, bool) {
+	t = t.Truncate(time.Minute)
+	from := t.Add(-limit)
+
+	for !t.Before(from) {
+		var prev time.Time
+		switch {
+		case e.month&(1<<int(t.Month())) == 0:
+			// Последняя минута предыдущего месяца
+			prev = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+		case !e.matchDay(t):
+			// Последняя минута предыдущего дня
+			prev = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+		case e.hour&(1<<t.Hour()) == 0:
+			// Последняя минута предыдущего часа
+			prev = t.Add(-time.Duration(t.Minute()+1) * time.Minute)
+		default:
+			m := prevBit(e.minute, t.Minute())
+			if m == t.Minute() {
+				return t, true
+			}
+			// Ближайшая совпадающая минута часа или последняя минута предыдущего часа
+			prev = t.Add(-time.Duration(t.Minute()-m) * time.Minute)
+		}
+
+		// Начало дня или месяца может не существовать при переходе на летнее время
+		if !prev.Before(t) {
+			prev = t.Add(-time.Minute)
+		}
+		t = prev
+	}
+	return time.Time{}, false
+}
+
+// Next возвращает первое совпадение с выражением позднее t,
+// но не позднее t + limit. Возвращает false, если совпадений нет.
+//
+// Несовпадающие месяц, день и час пропускаются целиком,
+// переходы на летнее время учитываются так же, как в Prev.
+func (e *Expression) Next(t time.Time, limit time.Duration) (time.Time, bool) {
+	to := t.Add(limit)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for !t.After(to) {
+		var next time.Time
+		switch {
+		case e.month&(1<<int(t.Month())) == 0:
+			// Начало следующего месяца
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !e.matchDay(t):
+			// Начало следующего дня
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case e.hour&(1<<t.Hour()) == 0:
+			// Начало следующего часа
+			next = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		default:
+			m := nextBit(e.minute, t.Minute())
+			if m == t.Minute() {
+				return t, true
+			}
+			// Ближайшая совпадающая минута часа или начало следующего часа
+			next = t.Add(time.Duration(m-t.Minute()) * time.Minute)
+		}
+
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}, false
+}
+
+// prevBit возвращает наибольшее значение маски, не превышающее v, или -1.
+func prevBit(mask uint64, v int) int {
+	for ; v >= 0; v-- {
+		if mask&(1<<v) != 0 {
+			return v
+		}
+	}
+	return -1
+}
+
+// nextBit возвращает наименьшее значение маски минут не меньше v или 60.
+func nextBit(mask uint64, v int) int {
+	for ; v < 60; v++ {
+		if mask&(1<<v) != 0 {
+			return v
+		}
+	}
+	return 60
+}
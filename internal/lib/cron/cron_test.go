@@ -0,0 +1,288 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"* * * * *", false},
+		{"*/15 9-18 * * 1-5", false},
+		{"0 0 1,15 * *", false},
+		{"30 2 * 1-12/3 0,7", false},
+		{"5/10 * * * *", false},
+		{"* * * *", true},
+		{"* * * * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 8", true},
+		{"5-1 * * * *", true},
+		{"*/0 * * * *", true},
+		{"a * * * *", true},
+		{"1-a * * * *", true},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidExpression) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.expr, err, ErrInvalidExpression)
+		}
+	}
+}
+
+func TestRestricted(t *testing.T) {
+	tests := []struct {
+		expr     string
+		dom, dow bool
+	}{
+		{"* * * * *", false, false},
+		{"* * */1 * */1", false, false},
+		{"* * 1-31 * 0-6", false, false},
+		{"* * * * 1-7", false, false},
+		{"* * */2 * *", true, false},
+		{"* * 1 * 1", true, true},
+		{"* * * * 0,7", false, true},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if e.domRestricted != tt.dom || e.dowRestricted != tt.dow {
+			t.Errorf("Parse(%q) restricted = %v, %v, want %v, %v",
+				tt.expr, e.domRestricted, e.dowRestricted, tt.dom, tt.dow)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	// 2026-10-18 — воскресенье
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		expr string
+		t    string
+		want bool
+	}{
+		{"* * * * *", "2026-10-18 12:34", true},
+		{"*/15 * * * *", "2026-10-18 12:45", true},
+		{"*/15 * * * *", "2026-10-18 12:46", false},
+		{"0 9-18 * * *", "2026-10-18 18:00", true},
+		{"0 9-18 * * *", "2026-10-18 19:00", false},
+		{"0 0 * * 7", "2026-10-18 00:00", true},
+		{"0 0 * * 0", "2026-10-18 00:00", true},
+		{"0 0 * * 1-5", "2026-10-18 00:00", false},
+		// День месяца или день недели
+		{"0 0 1 * 0", "2026-10-18 00:00", true},
+		{"0 0 18 * 1", "2026-10-18 00:00", true},
+		{"0 0 1 * 1", "2026-10-18 00:00", false},
+		// */1 не ограничивает день недели
+		{"0 0 1 * */1", "2026-10-18 00:00", false},
+		{"0 0 1 * */1", "2026-10-01 00:00", true},
+		{"0 0 * 2 *", "2026-10-18 00:00", false},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := e.Match(at(tt.t)); got != tt.want {
+			t.Errorf("%q.Match(%s) = %v, want %v", tt.expr, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestPrevNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	const day = 24 * time.Hour
+
+	tests := []struct {
+		name  string
+		expr  string
+		t     time.Time
+		limit time.Duration
+		prev  time.Time // Нулевое значение — совпадений нет
+		next  time.Time
+	}{
+		{
+			name:  "same minute",
+			expr:  "30 12 * * *",
+			t:     at("2026-10-18 12:30").Add(20 * time.Second),
+			limit: day,
+			prev:  at("2026-10-18 12:30"),
+			next:  at("2026-10-19 12:30"),
+		},
+		{
+			name:  "step",
+			expr:  "*/20 * * * *",
+			t:     at("2026-10-18 12:59"),
+			limit: time.Hour,
+			prev:  at("2026-10-18 12:40"),
+			next:  at("2026-10-18 13:00"),
+		},
+		{
+			name:  "previous month",
+			expr:  "0 0 1 * *",
+			t:     at("2026-10-18 12:00"),
+			limit: 60 * day,
+			prev:  at("2026-10-01 00:00"),
+			next:  at("2026-11-01 00:00"),
+		},
+		{
+			name:  "last day of february",
+			expr:  "15 23 29 2 *",
+			t:     at("2026-10-18 12:00"),
+			limit: 3 * 366 * day,
+			prev:  at("2024-02-29 23:15"),
+			next:  at("2028-02-29 23:15"),
+		},
+		{
+			name:  "out of limit",
+			expr:  "0 0 1 1 *",
+			t:     at("2026-10-18 12:00"),
+			limit: 30 * day,
+		},
+		{
+			name:  "impossible date",
+			expr:  "0 0 31 4 *",
+			t:     at("2026-10-18 12:00"),
+			limit: 366 * day,
+		},
+		{
+			name:  "weekday or day of month",
+			expr:  "0 8 13 * 5",
+			t:     at("2026-10-18 12:00"),
+			limit: 30 * day,
+			prev:  at("2026-10-16 08:00"),
+			next:  at("2026-10-23 08:00"),
+		},
+		{
+			// 2026-03-29 часы переводятся с 02:00 на 03:00
+			name:  "skipped hour",
+			expr:  "30 2 * * *",
+			t:     at("2026-03-29 12:00"),
+			limit: 2 * day,
+			prev:  at("2026-03-28 02:30"),
+			next:  at("2026-03-30 02:30"),
+		},
+		{
+			name:  "hour after skipped",
+			expr:  "0 3 29 3 *",
+			t:     at("2026-03-29 12:00"),
+			limit: day,
+			prev:  time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			// 2026-10-25 часы переводятся с 03:00 на 02:00
+			name:  "repeated hour",
+			expr:  "30 2 25 10 *",
+			t:     at("2026-10-25 12:00"),
+			limit: day,
+			prev:  time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prev, ok := e.Prev(tt.t, tt.limit)
+			if ok != !tt.prev.IsZero() || !prev.Equal(tt.prev) {
+				t.Errorf("Prev(%s) = %s, %v, want %s", tt.t, prev, ok, tt.prev)
+			}
+			next, ok := e.Next(tt.t, tt.limit)
+			if ok != !tt.next.IsZero() || !next.Equal(tt.next) {
+				t.Errorf("Next(%s) = %s, %v, want %s", tt.t, next, ok, tt.next)
+			}
+		})
+	}
+}
+
+// TestPrevNextExhaustive сравнивает Prev и Next с перебором минут.
+func TestPrevNextExhaustive(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exprs := []string{
+		"* * * * *",
+		"*/7 */5 * * *",
+		"59 23 * * *",
+		"0 2 * * *",
+		"30 2,3 * * 0",
+		"15 1-4 25-31 3,10 *",
+		"0 0 1,15 * 1",
+		"45 12 29 2 *",
+	}
+	starts := []time.Time{
+		time.Date(2026, 3, 28, 22, 10, 0, 0, berlin),
+		time.Date(2026, 10, 24, 23, 59, 30, 0, berlin),
+		time.Date(2026, 10, 25, 2, 30, 0, 0, berlin).Add(time.Hour),
+		time.Date(2026, 12, 31, 23, 30, 0, 0, time.UTC),
+	}
+	const limit = 3 * 24 * time.Hour
+
+	for _, expr := range exprs {
+		e, err := Parse(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, start := range starts {
+			wantPrev, wantPrevOK := time.Time{}, false
+			from := start.Truncate(time.Minute)
+			for m := from; !m.Before(start.Add(-limit)); m = m.Add(-time.Minute) {
+				if e.Match(m) {
+					wantPrev, wantPrevOK = m, true
+					break
+				}
+			}
+			if got, ok := e.Prev(start, limit); ok != wantPrevOK || !got.Equal(wantPrev) {
+				t.Errorf("%q.Prev(%s) = %s, %v, want %s, %v", expr, start, got, ok, wantPrev, wantPrevOK)
+			}
+
+			wantNext, wantNextOK := time.Time{}, false
+			for m := from.Add(time.Minute); !m.After(start.Add(limit)); m = m.Add(time.Minute) {
+				if e.Match(m) {
+					wantNext, wantNextOK = m, true
+					break
+				}
+			}
+			if got, ok := e.Next(start, limit); ok != wantNextOK || !got.Equal(wantNext) {
+				t.Errorf("%q.Next(%s) = %s, %v, want %s, %v", expr, start, got, ok, wantNext, wantNextOK)
+			}
+		}
+	}
+}
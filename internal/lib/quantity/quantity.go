@@ -0,0 +1,97 @@
+// Пакет quantity разбирает количества ресурсов в формате Kubernetes.
+//
+// Процессор задаётся в ядрах (2, 0.5) или милли-ядрах (500m),
+// память — в байтах с десятичными (k, M, G, T, P, E)
+// или двоичными (Ki, Mi, Gi, Ti, Pi, Ei) суффиксами.
+package quantity
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidQuantity = errors.New("invalid quantity")
+
+var memorySuffixes = []struct {
+	suffix string
+	factor int64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"Pi", 1 << 50},
+	{"Ei", 1 << 60},
+	{"k", 1e3},
+	{"M", 1e6},
+	{"G", 1e9},
+	{"T", 1e12},
+	{"P", 1e15},
+	{"E", 1e18},
+}
+
+// ParseCPU возвращает количество процессора в милли-ядрах.
+func ParseCPU(s string) (int64, error) {
+	num, factor := s, int64(1000)
+	if n, ok := strings.CutSuffix(s, "m"); ok {
+		num, factor = n, 1
+	}
+	return parse(s, num, factor)
+}
+
+// ParseMemory возвращает объём памяти в байтах.
+func ParseMemory(s string) (int64, error) {
+	num, factor := s, int64(1)
+	for _, m := range memorySuffixes {
+		if n, ok := strings.CutSuffix(s, m.suffix); ok {
+			num, factor = n, m.factor
+			break
+		}
+	}
+	return parse(s, num, factor)
+}
+
+// parse возвращает неотрицательное число num, умноженное на factor
+// и округлённое вверх до целого.
+func parse(s, num string, factor int64) (int64, error) {
+	if num == "" || strings.ContainsAny(num, "+-eE/") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuantity, s)
+	}
+
+	r, ok := new(big.Rat).SetString(num)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuantity, s)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(factor))
+
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if m.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("%w: %q is too large", ErrInvalidQuantity, s)
+	}
+	return q.Int64(), nil
+}
+
+// FormatCPU возвращает количество процессора в милли-ядрах в виде строки.
+func FormatCPU(m int64) string {
+	if m%1000 == 0 {
+		return fmt.Sprintf("%d", m/1000)
+	}
+	return fmt.Sprintf("%dm", m)
+}
+
+// FormatMemory возвращает объём памяти в байтах в виде строки
+// с наибольшим двоичным суффиксом, при котором значение остаётся целым.
+func FormatMemory(b int64) string {
+	for i := 5; i >= 0; i-- {
+		m := memorySuffixes[i]
+		if b != 0 && b%m.factor == 0 {
+			return fmt.Sprintf("%d%s", b/m.factor, m.suffix)
+		}
+	}
+	return fmt.Sprintf("%d", b)
+}
@@ -0,0 +1,114 @@
+package quantity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseCPU(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{"1", 1000, false},
+		{"16", 16000, false},
+		{"0.5", 500, false},
+		{".5", 500, false},
+		{"500m", 500, false},
+		{"0", 0, false},
+		{"0.0001", 1, false},
+		{"1.5m", 2, false},
+		{"", 0, true},
+		{"m", 0, true},
+		{"-1", 0, true},
+		{"+1", 0, true},
+		{"1e3", 0, true},
+		{"1/2", 0, true},
+		{"1Ki", 0, true},
+		{"one", 0, true},
+		{"99999999999999999999", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCPU(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseCPU(%q) = %d, %v, want %d, wantErr %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidQuantity) {
+			t.Errorf("ParseCPU(%q) error = %v, want %v", tt.s, err, ErrInvalidQuantity)
+		}
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"1k", 1000, false},
+		{"1Ki", 1024, false},
+		{"512Mi", 512 << 20, false},
+		{"1.5Gi", 3 << 29, false},
+		{"64Gi", 64 << 30, false},
+		{"2G", 2e9, false},
+		{"1Ti", 1 << 40, false},
+		{"1.5", 2, false},
+		{"", 0, true},
+		{"Mi", 0, true},
+		{"1K", 0, true},
+		{"1MiB", 0, true},
+		{"-1Gi", 0, true},
+		{"16Ei", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMemory(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMemory(%q) = %d, %v, want %d, wantErr %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	cpu := []struct {
+		m    int64
+		want string
+	}{
+		{0, "0"},
+		{500, "500m"},
+		{2000, "2"},
+		{1500, "1500m"},
+	}
+	for _, tt := range cpu {
+		if got := FormatCPU(tt.m); got != tt.want {
+			t.Errorf("FormatCPU(%d) = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+
+	mem := []struct {
+		b    int64
+		want string
+	}{
+		{0, "0"},
+		{1000, "1000"},
+		{1024, "1Ki"},
+		{512 << 20, "512Mi"},
+		{3 << 29, "1536Mi"},
+		{64 << 30, "64Gi"},
+	}
+	for _, tt := range mem {
+		if got := FormatMemory(tt.b); got != tt.want {
+			t.Errorf("FormatMemory(%d) = %q, want %q", tt.b, got, tt.want)
+		}
+	}
+
+	// Форматированное значение разбирается обратно
+	for _, tt := range mem {
+		if got, err := ParseMemory(tt.want); err != nil || got != tt.b {
+			t.Errorf("ParseMemory(%q) = %d, %v, want %d", tt.want, got, err, tt.b)
+		}
+	}
+}
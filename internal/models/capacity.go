@@ -0,0 +1,206 @@
+package models
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/quantity"
+)
+
+var ErrCapacityExceeded = errors.New("capacity exceeded")
+
+// Resources описывает ресурсы кластера или пода.
+type Resources struct {
+	CPU    int64 // Милли-ядра
+	Memory int64 // Байты
+}
+
+// ParseResources разбирает количества процессора и памяти клиента.
+func ParseResources(cpu, mem string) (Resources, error) {
+	c, err := quantity.ParseCPU(cpu)
+	if err != nil {
+		return Resources{}, fmt.Errorf("cpu: %w", err)
+	}
+	m, err := quantity.ParseMemory(mem)
+	if err != nil {
+		return Resources{}, fmt.Errorf("mem: %w", err)
+	}
+	return Resources{CPU: c, Memory: m}, nil
+}
+
+func (r Resources) Add(o Resources) Resources {
+	return Resources{CPU: r.CPU + o.CPU, Memory: r.Memory + o.Memory}
+}
+
+func (r Resources) Sub(o Resources) Resources {
+	return Resources{CPU: r.CPU - o.CPU, Memory: r.Memory - o.Memory}
+}
+
+// Times возвращает ресурсы n подов.
+func (r Resources) Times(n int) Resources {
+	return Resources{CPU: r.CPU * int64(n), Memory: r.Memory * int64(n)}
+}
+
+// CapacityError описывает нехватку ресурса кластера.
+type CapacityError struct {
+	Resource  string
+	Requested string
+	Available string
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("not enough %s: requested %s, available %s", e.Resource, e.Requested, e.Available)
+}
+
+func (e *CapacityError) Unwrap() error {
+	return ErrCapacityExceeded
+}
+
+// Политики вытеснения подов при нехватке ресурсов
+const (
+	PreemptNever = "never" // Поды не вытесняются
+	PreemptLower = "lower" // Вытесняются поды клиентов с меньшим приоритетом
+)
+
+// Capacity описывает ресурсы кластера, доступные подам, и политику вытеснения.
+// Нулевое значение ресурса снимает ограничение по нему.
+type Capacity struct {
+	Resources
+	Preemption string
+}
+
+// ParseCapacity разбирает ресурсы кластера. Пустые значения снимают ограничение.
+func ParseCapacity(cpu, mem, preemption string) (Capacity, error) {
+	if preemption != PreemptNever && preemption != PreemptLower {
+		return Capacity{}, fmt.Errorf("unknown preemption policy %q", preemption)
+	}
+
+	c := Capacity{Preemption: preemption}
+	if cpu != "" {
+		v, err := quantity.ParseCPU(cpu)
+		if err != nil {
+			return Capacity{}, fmt.Errorf("cpu: %w", err)
+		}
+		c.CPU = v
+	}
+	if mem != "" {
+		v, err := quantity.ParseMemory(mem)
+		if err != nil {
+			return Capacity{}, fmt.Errorf("mem: %w", err)
+		}
+		c.Memory = v
+	}
+	return c, nil
+}
+
+// IsZero сообщает, что ресурсы кластера не ограничены.
+func (c Capacity) IsZero() bool {
+	return c.CPU == 0 && c.Memory == 0
+}
+
+// Admit проверяет, что подам, требующим requested, хватит ресурсов кластера,
+// если остальные поды уже используют used.
+// Возвращает *CapacityError, если ресурсов недостаточно.
+func (c Capacity) Admit(used, requested Resources) error {
+	if c.CPU > 0 && used.CPU+requested.CPU > c.CPU {
+		return &CapacityError{
+			Resource:  "cpu",
+			Requested: quantity.FormatCPU(requested.CPU),
+			Available: quantity.FormatCPU(max(c.CPU-used.CPU, 0)),
+		}
+	}
+	if c.Memory > 0 && used.Memory+requested.Memory > c.Memory {
+		return &CapacityError{
+			Resource:  "mem",
+			Requested: quantity.FormatMemory(requested.Memory),
+			Available: quantity.FormatMemory(max(c.Memory-used.Memory, 0)),
+		}
+	}
+	return nil
+}
+
+// Candidate описывает статус, поды которого могут быть вытеснены.
+type Candidate struct {
+	Status    Status
+	Priority  float64
+	Resources Resources // Ресурсы одного пода клиента
+}
+
+// Preemption описывает поды статуса, выключенные для освобождения ресурсов.
+type Preemption struct {
+	Before Status
+	After  Status
+	Types  []string
+}
+
+// Preempt проверяет, что подам, требующим requested, хватит ресурсов кластера,
+// если остальные поды уже используют used. Если ресурсов недостаточно
+// и политика вытеснения это допускает, выбирает поды кандидатов, выключение
+// которых освобождает нужные ресурсы.
+//
+// Вытесняются только поды клиентов с приоритетом ниже priority,
+// начиная с клиентов с наименьшим приоритетом. Поды, не освобождающие
+// недостающих ресурсов, не вытесняются.
+// Возвращает *CapacityError, если ресурсов недостаточно даже после вытеснения.
+func (c Capacity) Preempt(used, requested Resources, priority float64, candidates []Candidate) ([]Preemption, error) {
+	err := c.Admit(used, requested)
+	if err == nil || c.Preemption != PreemptLower {
+		return nil, err
+	}
+
+	candidates = slices.Clone(candidates)
+	slices.SortStableFunc(candidates, func(a, b Candidate) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+
+	preempted := make([]Preemption, 0)
+	for _, cand := range candidates {
+		if cand.Priority >= priority {
+			break
+		}
+
+		p := Preemption{Before: cand.Status, After: cand.Status}
+		for i := len(PodTypes) - 1; i >= 0; i-- {
+			podType := PodTypes[i]
+			if !p.After.IsOn(podType) || !c.frees(used, requested, cand.Resources) {
+				continue
+			}
+			p.After.SetOn(podType, false)
+			p.Types = append(p.Types, podType)
+			used = used.Sub(cand.Resources)
+
+			if c.Admit(used, requested) == nil {
+				return append(preempted, p), nil
+			}
+		}
+		if len(p.Types) > 0 {
+			preempted = append(preempted, p)
+		}
+	}
+
+	return nil, err
+}
+
+// frees сообщает, освобождает ли под, требующий r, хотя бы один
+// из недостающих ресурсов.
+func (c Capacity) frees(used, requested, r Resources) bool {
+	return (c.CPU > 0 && used.CPU+requested.CPU > c.CPU && r.CPU > 0) ||
+		(c.Memory > 0 && used.Memory+requested.Memory > c.Memory && r.Memory > 0)
+}
+
+// PreemptionRecord описывает запись о вытеснении подов.
+type PreemptionRecord struct {
+	ID         int64
+	StatusID   int
+	ClientID   int
+	Types      []string
+	Priority   float64
+	ByStatusID int
+	ByClientID int
+	ByPriority float64
+	RequestID  string
+	CreatedAt  time.Time
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		cpu, mem   string
+		preemption string
+		want       Capacity
+		wantErr    bool
+	}{
+		{"unlimited", "", "", PreemptNever, Capacity{Preemption: PreemptNever}, false},
+		{"cpu only", "16", "", PreemptLower, Capacity{Resources{CPU: 16000}, PreemptLower}, false},
+		{"both", "1500m", "64Gi", PreemptNever, Capacity{Resources{CPU: 1500, Memory: 64 << 30}, PreemptNever}, false},
+		{"bad cpu", "x", "", PreemptNever, Capacity{}, true},
+		{"bad mem", "", "1GB", PreemptNever, Capacity{}, true},
+		{"bad policy", "", "", "always", Capacity{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCapacity(tt.cpu, tt.mem, tt.preemption)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseCapacity() = %+v, %v, want %+v, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	r, err := ParseResources("250m", "512Mi")
+	if err != nil || r != (Resources{CPU: 250, Memory: 512 << 20}) {
+		t.Errorf("ParseResources() = %+v, %v", r, err)
+	}
+	if _, err := ParseResources("", "512Mi"); err == nil {
+		t.Error("ParseResources() with empty cpu: expected error")
+	}
+	if _, err := ParseResources("1", "lots"); err == nil {
+		t.Error("ParseResources() with invalid mem: expected error")
+	}
+}
+
+func TestCapacityAdmit(t *testing.T) {
+	c := Capacity{Resources: Resources{CPU: 2000, Memory: 4 << 30}}
+
+	tests := []struct {
+		name      string
+		capacity  Capacity
+		used      Resources
+		requested Resources
+		resource  string // Пустое значение — ресурсов достаточно
+	}{
+		{"fits", c, Resources{1000, 1 << 30}, Resources{1000, 3 << 30}, ""},
+		{"not enough cpu", c, Resources{1500, 0}, Resources{1000, 0}, "cpu"},
+		{"not enough mem", c, Resources{0, 3 << 30}, Resources{0, 2 << 30}, "mem"},
+		{"cpu unlimited", Capacity{Resources: Resources{Memory: 1 << 30}}, Resources{1e6, 0}, Resources{1e6, 1 << 30}, ""},
+		{"unlimited", Capacity{}, Resources{1e6, 1 << 40}, Resources{1e6, 1 << 40}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.capacity.Admit(tt.used, tt.requested)
+			if tt.resource == "" {
+				if err != nil {
+					t.Fatalf("Admit() error = %v", err)
+				}
+				return
+			}
+
+			var ce *CapacityError
+			if !errors.As(err, &ce) || ce.Resource != tt.resource {
+				t.Fatalf("Admit() error = %v, want not enough %s", err, tt.resource)
+			}
+			if !errors.Is(err, ErrCapacityExceeded) {
+				t.Errorf("Admit() error = %v, want %v", err, ErrCapacityExceeded)
+			}
+		})
+	}
+
+	err := c.Admit(Resources{CPU: 1500}, Resources{CPU: 1000})
+	if want := "not enough cpu: requested 1, available 500m"; err == nil || err.Error() != want {
+		t.Errorf("Admit() error = %v, want %q", err, want)
+	}
+}
+
+func TestCapacityPreempt(t *testing.T) {
+	cpu := func(m int64) Resources { return Resources{CPU: m} }
+
+	// Поды статусов 1 (X, Y) и 2 (Z) требуют по 1 ядру,
+	// под статуса 3 с наименьшим приоритетом требует только памяти
+	candidates := []Candidate{
+		{Status: Status{ID: 2, ClientID: 2, Z: true}, Priority: 0.2, Resources: cpu(1000)},
+		{Status: Status{ID: 1, ClientID: 1, X: true, Y: true}, Priority: 0.1, Resources: cpu(1000)},
+		{Status: Status{ID: 3, ClientID: 3, X: true}, Priority: 0, Resources: Resources{Memory: 1 << 30}},
+	}
+
+	type preempted struct {
+		statusID int
+		types    []string
+	}
+
+	tests := []struct {
+		name       string
+		preemption string
+		used       Resources
+		requested  Resources
+		priority   float64
+		want       []preempted
+		wantErr    bool
+	}{
+		{
+			name:       "fits",
+			preemption: PreemptLower,
+			used:       cpu(3000),
+			requested:  cpu(1000),
+			priority:   1,
+		},
+		{
+			name:       "never preempts",
+			preemption: PreemptNever,
+			used:       cpu(3000),
+			requested:  cpu(2000),
+			priority:   1,
+			wantErr:    true,
+		},
+		{
+			name:       "lowest priority first",
+			preemption: PreemptLower,
+			used:       cpu(3000),
+			requested:  cpu(2000),
+			priority:   1,
+			want:       []preempted{{1, []string{PodY}}},
+		},
+		{
+			name:       "several clients",
+			preemption: PreemptLower,
+			used:       cpu(3000),
+			requested:  cpu(4000),
+			priority:   1,
+			want:       []preempted{{1, []string{PodY, PodX}}, {2, []string{PodZ}}},
+		},
+		{
+			name:       "higher priority is kept",
+			preemption: PreemptLower,
+			used:       cpu(3000),
+			requested:  cpu(4000),
+			priority:   0.2,
+			wantErr:    true,
+		},
+		{
+			name:       "not enough after preemption",
+			preemption: PreemptLower,
+			used:       cpu(3000),
+			requested:  cpu(5000),
+			priority:   1,
+			wantErr:    true,
+		},
+		{
+			name:       "equal priority is kept",
+			preemption: PreemptLower,
+			used:       cpu(3000),
+			requested:  cpu(2000),
+			priority:   0.1,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Capacity{Resources: cpu(4000), Preemption: tt.preemption}
+			got, err := c.Preempt(tt.used, tt.requested, tt.priority, candidates)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrCapacityExceeded) || got != nil {
+					t.Fatalf("Preempt() = %+v, %v, want capacity error", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Preempt() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Preempt() = %+v, want %+v", got, tt.want)
+			}
+			for i, p := range got {
+				w := tt.want[i]
+				if p.After.ID != w.statusID || !slices.Equal(p.Types, w.types) {
+					t.Errorf("preemption %d = status %d %v, want status %d %v", i, p.After.ID, p.Types, w.statusID, w.types)
+				}
+				for _, podType := range p.Types {
+					if !p.Before.IsOn(podType) || p.After.IsOn(podType) {
+						t.Errorf("preemption %d: pod %s is not switched off", i, podType)
+					}
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,12 @@ import (
 	"time"
 )
 
+// Стратегии перезапуска подов клиента
+const (
+	RestartRecreate = "recreate" // Под удаляется и создаётся заново
+	RestartSurge    = "surge"    // Новый под создаётся до удаления прежнего
+)
+
 type Client struct {
 	ID        int
 	Name      string
@@ -13,6 +19,7 @@ type Client struct {
 	CPU       string
 	Memory    string
 	Priority  float64
+	Restart   string // Стратегия перезапуска подов
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
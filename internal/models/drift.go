@@ -0,0 +1,114 @@
+package models
+
+// Pod описывает под статуса.
+type Pod struct {
+	ID       string
+	Type     string
+	StatusID int
+	ClientID int
+}
+
+// Drift описывает расхождение между статусами и запущенными подами.
+type Drift struct {
+	// Поды, которые должны быть запущены, но отсутствуют
+	Missing []Pod
+
+	// Поды статусов, которые запущены, но должны быть выключены
+	Unexpected []Pod
+
+	// Запущенные поды статусов с устаревшими именами (например, после
+	// переименования клиента или перезапуска surge)
+	Stale []Pod
+
+	// Запущенные поды, не принадлежащие ни одному из статусов
+	Orphans []string
+}
+
+// DetectDrift сравнивает статусы с запущенными подами.
+// Принадлежность подов статусам определяется правилом именования n.
+func DetectDrift(n *PodNamer, statuses []Status, pods []string) Drift {
+	running := make(map[string]struct{}, len(pods))
+	for _, p := range pods {
+		running[p] = struct{}{}
+	}
+
+	d := Drift{
+		Missing:    make([]Pod, 0),
+		Unexpected: make([]Pod, 0),
+		Stale:      make([]Pod, 0),
+		Orphans:    make([]string, 0),
+	}
+
+	known := make(map[string]struct{}, len(statuses)*len(PodTypes))
+	byStatus := make(map[int]int, len(statuses))
+	byClient := make(map[int]int, len(statuses))
+	// Статусы, имена подов которых не удалось сформировать
+	unnamed := make(map[int]struct{})
+
+	for i := range statuses {
+		byStatus[statuses[i].ID] = i
+		byClient[statuses[i].ClientID] = i
+
+		for _, podType := range PodTypes {
+			podID, err := n.Name(podType, &statuses[i])
+			if err != nil {
+				unnamed[statuses[i].ID] = struct{}{}
+				continue
+			}
+			pod := Pod{
+				ID:       podID,
+				Type:     podType,
+				StatusID: statuses[i].ID,
+				ClientID: statuses[i].ClientID,
+			}
+			known[pod.ID] = struct{}{}
+
+			isOn := statuses[i].IsOn(podType)
+			_, isRunning := running[pod.ID]
+			switch {
+			case isOn && !isRunning:
+				d.Missing = append(d.Missing, pod)
+			case !isOn && isRunning:
+				d.Unexpected = append(d.Unexpected, pod)
+			}
+		}
+	}
+
+	owner := func(ref PodRef) (int, bool) {
+		if ref.StatusID != 0 {
+			i, ok := byStatus[ref.StatusID]
+			if !ok || (ref.ClientID != 0 && statuses[i].ClientID != ref.ClientID) {
+				return 0, false
+			}
+			return i, true
+		}
+		i, ok := byClient[ref.ClientID]
+		return i, ok
+	}
+
+	for _, p := range pods {
+		if _, ok := known[p]; ok {
+			continue
+		}
+
+		// Под статуса, имя которого изменилось (например, после
+		// переименования клиента), заменяется подом с актуальным именем
+		if ref, ok := n.Parse(p); ok {
+			if i, ok := owner(ref); ok {
+				if _, ok := unnamed[statuses[i].ID]; !ok {
+					d.Stale = append(d.Stale, Pod{
+						ID:       p,
+						Type:     ref.Type,
+						StatusID: statuses[i].ID,
+						ClientID: statuses[i].ClientID,
+					})
+				}
+				continue
+			}
+		}
+
+		d.Orphans = append(d.Orphans, p)
+	}
+
+	return d
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetectDrift(t *testing.T) {
+	statuses := []Status{
+		{ID: 1, ClientID: 1, X: true, Y: true},
+		{ID: 2, ClientID: 2, Z: true, Gen: [3]int{0, 0, 2}},
+	}
+	pods := []string{"X-1", "Z-1", "Z-2", "Z-2-g2", "Y-3", "other"}
+
+	d := DetectDrift(nil, statuses, pods)
+
+	ids := func(pods []Pod) []string {
+		ids := make([]string, 0, len(pods))
+		for _, p := range pods {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	if got, want := ids(d.Missing), []string{"Y-1"}; !slices.Equal(got, want) {
+		t.Errorf("missing = %v, want %v", got, want)
+	}
+	if got, want := ids(d.Unexpected), []string{"Z-1"}; !slices.Equal(got, want) {
+		t.Errorf("unexpected = %v, want %v", got, want)
+	}
+	if got, want := ids(d.Stale), []string{"Z-2"}; !slices.Equal(got, want) {
+		t.Errorf("stale = %v, want %v", got, want)
+	}
+	if len(d.Stale) == 1 && d.Stale[0].StatusID != 2 {
+		t.Errorf("stale pod status = %d, want 2", d.Stale[0].StatusID)
+	}
+	if got, want := d.Orphans, []string{"Y-3", "other"}; !slices.Equal(got, want) {
+		t.Errorf("orphans = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,294 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+var ErrInvalidPodName = errors.New("invalid pod name")
+
+// Максимальная длина метки DNS (RFC 1123)
+const maxPodNameLength = 63
+
+var dns1123Label = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Суффикс поколения пода, созданного перезапуском surge
+var generationSuffix = regexp.MustCompile(`^(.+)-g([1-9][0-9]*)$`)
+
+// PodNameData содержит значения, доступные в шаблоне имени пода.
+type PodNameData struct {
+	Env      string
+	Type     string // Тип пода в нижнем регистре
+	StatusID int
+	Client   PodNameClient
+}
+
+type PodNameClient struct {
+	ID   int
+	Name string // Имя клиента, приведённое к допустимым символам
+}
+
+// PodRef описывает под, восстановленный по имени.
+// Нулевые StatusID или ClientID означают, что шаблон их не содержит.
+type PodRef struct {
+	Type       string
+	StatusID   int
+	ClientID   int
+	Generation int
+}
+
+// Поля шаблона и соответствующие им выражения.
+// Значение Env известно заранее и подставляется как есть.
+var podNameFields = map[string]string{
+	"Env":         "",
+	"Type":        podTypePattern(),
+	"StatusID":    `[1-9][0-9]*`,
+	"Client.ID":   `[1-9][0-9]*`,
+	"Client.Name": `[a-z0-9](?:[-a-z0-9]*[a-z0-9])?`,
+}
+
+func podTypePattern() string {
+	types := make([]string, 0, len(PodTypes))
+	for _, podType := range PodTypes {
+		types = append(types, regexp.QuoteMeta(strings.ToLower(podType)))
+	}
+	return strings.Join(types, "|")
+}
+
+// PodNamer формирует имена подов по шаблону и восстанавливает по имени
+// тип пода и его владельца.
+//
+// Нулевое значение (и nil) использует прежнее правило именования PodID.
+type PodNamer struct {
+	env    string
+	tmpl   *template.Template
+	re     *regexp.Regexp
+	fields []string // Поля шаблона в порядке групп выражения re
+}
+
+// NewPodNamer создаёт PodNamer по шаблону text/template.
+// Пустой шаблон соответствует прежнему правилу именования PodID.
+//
+// Шаблон может содержать только текст и подстановки полей PodNameData
+// и должен включать {{.Type}} и хотя бы одно из {{.StatusID}}, {{.Client.ID}},
+// чтобы имя пода можно было разобрать.
+func NewPodNamer(text, env string) (*PodNamer, error) {
+	const op = "models.NewPodNamer"
+
+	if text == "" {
+		return &PodNamer{}, nil
+	}
+
+	tmpl, err := template.New("pod").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n := &PodNamer{
+		env:  env,
+		tmpl: tmpl,
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			pattern.WriteString(regexp.QuoteMeta(string(node.Text)))
+		case *parse.ActionNode:
+			field, ok := actionField(node)
+			if !ok {
+				return nil, fmt.Errorf("%s: unsupported template action %s", op, node)
+			}
+			expr, ok := podNameFields[field]
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown template field .%s", op, field)
+			}
+			if field == "Env" {
+				pattern.WriteString(regexp.QuoteMeta(env))
+				continue
+			}
+			pattern.WriteString("(" + expr + ")")
+			n.fields = append(n.fields, field)
+		default:
+			return nil, fmt.Errorf("%s: unsupported template node %s", op, node)
+		}
+	}
+	pattern.WriteString("$")
+
+	if !slices.Contains(n.fields, "Type") {
+		return nil, fmt.Errorf("%s: template must contain .Type", op)
+	}
+	if !slices.Contains(n.fields, "StatusID") && !slices.Contains(n.fields, "Client.ID") {
+		return nil, fmt.Errorf("%s: template must contain .StatusID or .Client.ID", op)
+	}
+
+	n.re, err = regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Проверка шаблона на произвольном статусе
+	sample := &Status{ID: 1, ClientID: 1, ClientName: "client"}
+	if err := n.Check(sample); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// actionField возвращает имя поля, если действие является подстановкой поля.
+func actionField(node *parse.ActionNode) (string, bool) {
+	if len(node.Pipe.Decl) != 0 || len(node.Pipe.Cmds) != 1 {
+		return "", false
+	}
+	args := node.Pipe.Cmds[0].Args
+	if len(args) != 1 {
+		return "", false
+	}
+	f, ok := args[0].(*parse.FieldNode)
+	if !ok {
+		return "", false
+	}
+	return strings.Join(f.Ident, "."), true
+}
+
+// Name возвращает имя пода заданного типа.
+// К имени пода с ненулевым поколением добавляется суффикс -g<поколение>.
+// Возвращает ErrInvalidPodName, если имя не является меткой DNS-1123
+// или не может быть однозначно разобрано.
+func (n *PodNamer) Name(podType string, s *Status) (string, error) {
+	gen := s.Generation(podType)
+	if n == nil || n.tmpl == nil {
+		return withGeneration(PodID(podType, s.ID), gen), nil
+	}
+
+	data := PodNameData{
+		Env:      n.env,
+		Type:     strings.ToLower(podType),
+		StatusID: s.ID,
+		Client: PodNameClient{
+			ID:   s.ClientID,
+			Name: sanitizeName(s.ClientName),
+		},
+	}
+
+	var b strings.Builder
+	if err := n.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidPodName, err)
+	}
+	name := withGeneration(b.String(), gen)
+
+	if len(name) > maxPodNameLength || !dns1123Label.MatchString(name) {
+		return "", fmt.Errorf("%w: %q is not a DNS-1123 label", ErrInvalidPodName, name)
+	}
+
+	ref, ok := n.Parse(name)
+	if !ok || ref.Type != podType || ref.Generation != gen ||
+		(ref.StatusID != 0 && ref.StatusID != s.ID) ||
+		(ref.ClientID != 0 && ref.ClientID != s.ClientID) {
+		return "", fmt.Errorf("%w: %q is ambiguous", ErrInvalidPodName, name)
+	}
+
+	return name, nil
+}
+
+// Check проверяет, что имена всех подов статуса допустимы.
+func (n *PodNamer) Check(s *Status) error {
+	for _, podType := range PodTypes {
+		if _, err := n.Name(podType, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse разбирает имя пода, в том числе с суффиксом поколения.
+// Возвращает false, если имя не соответствует правилу именования.
+func (n *PodNamer) Parse(name string) (PodRef, bool) {
+	if m := generationSuffix.FindStringSubmatch(name); m != nil {
+		if ref, ok := n.parse(m[1]); ok {
+			ref.Generation, _ = strconv.Atoi(m[2])
+			return ref, true
+		}
+	}
+	return n.parse(name)
+}
+
+// parse разбирает имя пода без суффикса поколения.
+func (n *PodNamer) parse(name string) (PodRef, bool) {
+	if n == nil || n.tmpl == nil {
+		podType, statusID, ok := parsePodID(name)
+		return PodRef{Type: podType, StatusID: statusID}, ok
+	}
+
+	m := n.re.FindStringSubmatch(name)
+	if m == nil {
+		return PodRef{}, false
+	}
+
+	ref := PodRef{}
+	set := func(dst *int, v string) bool {
+		id, err := strconv.Atoi(v)
+		if err != nil || (*dst != 0 && *dst != id) {
+			return false
+		}
+		*dst = id
+		return true
+	}
+
+	for i, field := range n.fields {
+		v := m[i+1]
+		switch field {
+		case "Type":
+			podType := strings.ToUpper(v)
+			if ref.Type != "" && ref.Type != podType {
+				return PodRef{}, false
+			}
+			ref.Type = podType
+		case "StatusID":
+			if !set(&ref.StatusID, v) {
+				return PodRef{}, false
+			}
+		case "Client.ID":
+			if !set(&ref.ClientID, v) {
+				return PodRef{}, false
+			}
+		}
+	}
+
+	return ref, true
+}
+
+// withGeneration добавляет к имени пода суффикс ненулевого поколения.
+func withGeneration(name string, gen int) string {
+	if gen == 0 {
+		return name
+	}
+	return name + "-g" + strconv.Itoa(gen)
+}
+
+// sanitizeName приводит имя к символам, допустимым в метке DNS:
+// строчные латинские буквы, цифры и дефисы между ними.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
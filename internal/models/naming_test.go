@@ -0,0 +1,156 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewPodNamer(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"status", "{{.Type}}-{{.StatusID}}", false},
+		{"client", "{{.Env}}-{{.Client.Name}}-{{.Type}}-{{.Client.ID}}", false},
+		{"no type", "pod-{{.StatusID}}", true},
+		{"no id", "{{.Type}}-{{.Client.Name}}", true},
+		{"unknown field", "{{.Type}}-{{.StatusID}}-{{.Host}}", true},
+		{"unsupported action", "{{.Type}}-{{.StatusID}}{{if .Env}}-x{{end}}", true},
+		{"function call", "{{.Type}}-{{printf \"%d\" .StatusID}}", true},
+		{"not a DNS label", "{{.Type}}_{{.StatusID}}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPodNamer(tt.tmpl, "prod")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPodNamer(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPodNamerRoundTrip(t *testing.T) {
+	templates := []string{
+		"",
+		"{{.Type}}-{{.StatusID}}",
+		"{{.Env}}-{{.Client.Name}}-{{.Type}}-{{.Client.ID}}",
+		"{{.Type}}-{{.Client.ID}}-{{.StatusID}}",
+		"pod-{{.Client.Name}}-{{.StatusID}}-{{.Type}}",
+	}
+	statuses := []Status{
+		{ID: 1, ClientID: 1, ClientName: "client"},
+		{ID: 168317, ClientID: 42, ClientName: "ACME Corp."},
+		{ID: 7, ClientID: 9, ClientName: "x-1-y-2"},
+	}
+
+	for _, text := range templates {
+		n, err := NewPodNamer(text, "prod")
+		if err != nil {
+			t.Fatalf("NewPodNamer(%q): %v", text, err)
+		}
+		for _, s := range statuses {
+			for _, podType := range PodTypes {
+				name, err := n.Name(podType, &s)
+				if err != nil {
+					t.Fatalf("%q: Name(%s, %d): %v", text, podType, s.ID, err)
+				}
+
+				ref, ok := n.Parse(name)
+				if !ok {
+					t.Fatalf("%q: Parse(%q) failed", text, name)
+				}
+				if ref.Type != podType || ref.Generation != 0 {
+					t.Errorf("%q: Parse(%q) = %+v, want type %s", text, name, ref, podType)
+				}
+				if strings.Contains(text, ".StatusID") || text == "" {
+					if ref.StatusID != s.ID {
+						t.Errorf("%q: Parse(%q) status = %d, want %d", text, name, ref.StatusID, s.ID)
+					}
+				}
+				if strings.Contains(text, ".Client.ID") && ref.ClientID != s.ClientID {
+					t.Errorf("%q: Parse(%q) client = %d, want %d", text, name, ref.ClientID, s.ClientID)
+				}
+			}
+		}
+	}
+}
+
+func TestPodNamerParse(t *testing.T) {
+	n, err := NewPodNamer("{{.Env}}-{{.Client.Name}}-{{.Type}}-{{.Client.ID}}", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		pod  string
+		want PodRef
+		ok   bool
+	}{
+		{"valid", "prod-acme-x-42", PodRef{Type: PodX, ClientID: 42}, true},
+		{"generation", "prod-acme-x-42-g3", PodRef{Type: PodX, ClientID: 42, Generation: 3}, true},
+		{"other env", "dev-acme-x-42", PodRef{}, false},
+		{"unknown type", "prod-acme-w-42", PodRef{}, false},
+		{"leading zero", "prod-acme-x-042", PodRef{}, false},
+		{"legacy name", "X-42", PodRef{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, ok := n.Parse(tt.pod)
+			if ok != tt.ok || ref != tt.want {
+				t.Errorf("Parse(%q) = %+v, %v, want %+v, %v", tt.pod, ref, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPodNamerNameTooLong(t *testing.T) {
+	n, err := NewPodNamer("{{.Client.Name}}-{{.Type}}-{{.StatusID}}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Status{ID: 1, ClientID: 1, ClientName: strings.Repeat("a", 60)}
+	if _, err := n.Name(PodX, s); !errors.Is(err, ErrInvalidPodName) {
+		t.Errorf("Name() error = %v, want %v", err, ErrInvalidPodName)
+	}
+}
+
+func TestPodNamerNameAmbiguous(t *testing.T) {
+	n, err := NewPodNamer("{{.Type}}{{.StatusID}}{{.Client.ID}}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// x111 разбирается как статус 11 и клиент 1
+	if _, err := n.Name(PodX, &Status{ID: 11, ClientID: 1}); err != nil {
+		t.Errorf("Name() error = %v", err)
+	}
+	if _, err := n.Name(PodX, &Status{ID: 1, ClientID: 11}); !errors.Is(err, ErrInvalidPodName) {
+		t.Errorf("Name() error = %v, want %v", err, ErrInvalidPodName)
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"client", "client"},
+		{"ACME Corp.", "acme-corp"},
+		{"  --a__b--  ", "a-b"},
+		{"Клиент 1", "1"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeName(tt.name); got != tt.want {
+			t.Errorf("sanitizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
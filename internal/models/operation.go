@@ -1,8 +1,11 @@
 package models
 
 import (
+	"cmp"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 )
 
 type OpCode int
@@ -11,74 +14,242 @@ const (
 	_ OpCode = iota
 	OpCodeCreate
 	OpCodeDelete
+	OpCodeRestart
 
-	opLabelCreate = "CREATE"
-	opLabelDelete = "DELETE"
+	opLabelCreate  = "CREATE"
+	opLabelDelete  = "DELETE"
+	opLabelRestart = "RESTART"
 )
 
+func (c OpCode) String() string {
+	switch c {
+	case OpCodeCreate:
+		return opLabelCreate
+	case OpCodeDelete:
+		return opLabelDelete
+	case OpCodeRestart:
+		return opLabelRestart
+	default:
+		return "UNKNOWN"
+	}
+}
+
 type PodOperation struct {
-	PodID string
-	Code  OpCode
+	ID       int64
+	PodID    string
+	Code     OpCode
+	StatusID int
+	ClientID int
+
+	// Прежнее имя пода при перезапуске surge:
+	// под PodID создаётся, а под PrevPodID удаляется после его запуска
+	PrevPodID string
+
+	// Идентификатор запроса, породившего операцию
+	RequestID string
+
+	// Приоритет клиента, которому принадлежит под
+	Priority float64
+
+	// Количество неудачных попыток выполнения
+	Attempts int
 }
 
-func (po PodOperation) LogValue() slog.Value {
-	var a string
-	switch po.Code {
-	case OpCodeCreate:
-		a = opLabelCreate
-	case OpCodeDelete:
-		a = opLabelDelete
+// DeadLetter описывает операцию, которую не удалось выполнить
+// за максимальное количество попыток.
+type DeadLetter struct {
+	Op       PodOperation
+	Error    string
+	FailedAt time.Time
+}
+
+// Состояния операции с подом
+const (
+	OpStatePending   = "pending"
+	OpStateRunning   = "running"
+	OpStateSucceeded = "succeeded"
+	OpStateFailed    = "failed"
+	OpStateCancelled = "cancelled" // Операция свёрнута с другой и не выполнялась
+)
+
+// OperationStatus описывает текущее состояние зарегистрированной операции.
+type OperationStatus struct {
+	Op        PodOperation
+	State     string
+	Error     string
+	UpdatedAt time.Time
+}
+
+// IsFinal сообщает, завершена ли операция.
+func (s *OperationStatus) IsFinal() bool {
+	switch s.State {
+	case OpStateSucceeded, OpStateFailed, OpStateCancelled:
+		return true
 	default:
-		a = "UNKNOWN"
+		return false
+	}
+}
+
+// OperationRecord описывает однократное выполнение операции с подом.
+type OperationRecord struct {
+	ID         int64
+	Op         PodOperation
+	Attempt    int
+	Result     string
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+func (po PodOperation) LogValue() slog.Value {
+	if po.PrevPodID != "" {
+		return slog.StringValue(fmt.Sprintf("<%s> %s -> %s", po.Code, po.PrevPodID, po.PodID))
+	}
+	return slog.StringValue(fmt.Sprintf("<%s> %s", po.Code, po.PodID))
+}
+
+func OpCreate(podID string, statusID int) PodOperation {
+	return PodOperation{
+		PodID:    podID,
+		Code:     OpCodeCreate,
+		StatusID: statusID,
 	}
-	return slog.StringValue(fmt.Sprintf("<%s> %s", a, po.PodID))
 }
 
-func OpCreate(podID string) PodOperation {
+func OpDelete(podID string, statusID int) PodOperation {
 	return PodOperation{
-		PodID: podID,
-		Code:  OpCodeCreate,
+		PodID:    podID,
+		Code:     OpCodeDelete,
+		StatusID: statusID,
 	}
 }
 
-func OpDelete(podID string) PodOperation {
+// OpRestart возвращает операцию перезапуска пода: удаление с последующим созданием.
+func OpRestart(podID string, statusID int) PodOperation {
 	return PodOperation{
-		PodID: podID,
-		Code:  OpCodeDelete,
+		PodID:    podID,
+		Code:     OpCodeRestart,
+		StatusID: statusID,
+	}
+}
+
+// OpSurge возвращает операцию перезапуска пода с созданием нового пода podID
+// до удаления прежнего пода prevPodID.
+func OpSurge(podID, prevPodID string, statusID int) PodOperation {
+	return PodOperation{
+		PodID:     podID,
+		PrevPodID: prevPodID,
+		Code:      OpCodeRestart,
+		StatusID:  statusID,
+	}
+}
+
+// restartOperation возвращает операцию перезапуска пода podID статуса s
+// согласно стратегии перезапуска клиента.
+// При стратегии surge увеличивает поколение пода в s.
+func restartOperation(n *PodNamer, s *Status, podType, podID string) (PodOperation, error) {
+	if s.RestartStrategy != RestartSurge {
+		return OpRestart(podID, s.ID), nil
 	}
+
+	gen := s.Generation(podType)
+	s.SetGeneration(podType, gen+1)
+
+	next, err := n.Name(podType, s)
+	if err != nil {
+		s.SetGeneration(podType, gen)
+		return PodOperation{}, err
+	}
+	return OpSurge(next, podID, s.ID), nil
 }
 
 // UpdateOperations возвращает список операций соответствующих изменению статуса подов.
-func UpdateOperations(s, sBefore *Status, needRestart bool) []PodOperation {
+// Имена подов формируются n по данным статуса s.
+// Перезапуск surge увеличивает поколение пода в s.
+func UpdateOperations(n *PodNamer, s, sBefore *Status, needRestart bool) ([]PodOperation, error) {
 	if s == nil || sBefore == nil || s.ID != sBefore.ID {
-		return nil
+		return nil, nil
 	}
 
 	ops := make([]PodOperation, 0)
 
+	var errName error
 	updatePod := func(podName string, isOn, wasOn bool, needRestart bool) {
-		podID := fmt.Sprintf("%s-%d", podName, s.ID)
+		if !isOn && !wasOn {
+			return
+		}
+		podID, err := n.Name(podName, s)
+		if err != nil {
+			errName = err
+			return
+		}
 		if isOn != wasOn {
 			if isOn {
-				ops = append(ops, OpCreate(podID))
+				ops = append(ops, OpCreate(podID, s.ID))
 			} else {
-				ops = append(ops, OpDelete(podID))
+				ops = append(ops, OpDelete(podID, s.ID))
 			}
 		} else if wasOn && needRestart {
-			ops = append(ops, OpDelete(podID), OpCreate(podID))
+			po, err := restartOperation(n, s, podName, podID)
+			if err != nil {
+				errName = err
+				return
+			}
+			ops = append(ops, po)
 		}
 	}
 
-	updatePod("X", s.X, sBefore.X, needRestart)
-	updatePod("Y", s.Y, sBefore.Y, needRestart)
-	updatePod("Z", s.Z, sBefore.Z, needRestart)
+	updatePod(PodX, s.X, sBefore.X, needRestart)
+	updatePod(PodY, s.Y, sBefore.Y, needRestart)
+	updatePod(PodZ, s.Z, sBefore.Z, needRestart)
 
-	return ops
+	if errName != nil {
+		return nil, errName
+	}
+	for i := range ops {
+		ops[i].ClientID = s.ClientID
+	}
+	return ops, nil
 }
 
-func DeleteOperations(s *Status) []PodOperation {
+func DeleteOperations(n *PodNamer, s *Status) ([]PodOperation, error) {
 	if s == nil {
-		return nil
+		return nil, nil
+	}
+	// Имена удаляемых подов формируются с текущими поколениями
+	sAfter := &Status{ID: s.ID, ClientID: s.ClientID, ClientName: s.ClientName, Gen: s.Gen}
+	return UpdateOperations(n, sAfter, s, false)
+}
+
+// SyncOperations возвращает список операций, приводящих множество
+// запущенных подов в соответствие со статусами.
+// Поды, не принадлежащие ни одному из статусов, и поды статусов
+// с устаревшими именами не затрагиваются.
+func SyncOperations(n *PodNamer, statuses []Status, pods []string) []PodOperation {
+	d := DetectDrift(n, statuses, pods)
+
+	ops := make([]PodOperation, 0, len(d.Missing)+len(d.Unexpected))
+	for _, p := range d.Missing {
+		po := OpCreate(p.ID, p.StatusID)
+		po.ClientID = p.ClientID
+		ops = append(ops, po)
+	}
+	for _, p := range d.Unexpected {
+		po := OpDelete(p.ID, p.StatusID)
+		po.ClientID = p.ClientID
+		ops = append(ops, po)
 	}
-	return UpdateOperations(&Status{ID: s.ID}, s, false)
+
+	return ops
+}
+
+// SortOperations упорядочивает операции по убыванию приоритета клиента.
+// При равном приоритете операции упорядочиваются по порядку регистрации.
+func SortOperations(ops []PodOperation) {
+	slices.SortStableFunc(ops, func(a, b PodOperation) int {
+		if c := cmp.Compare(b.Priority, a.Priority); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
 }
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSortOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []PodOperation
+		want []int64
+	}{
+		{
+			name: "by priority",
+			ops:  []PodOperation{{ID: 1, Priority: 1}, {ID: 2, Priority: 3}, {ID: 3, Priority: 2}},
+			want: []int64{2, 3, 1},
+		},
+		{
+			name: "equal priority by id",
+			ops:  []PodOperation{{ID: 3, Priority: 1}, {ID: 1, Priority: 1}, {ID: 2, Priority: 1}},
+			want: []int64{1, 2, 3},
+		},
+		{
+			name: "fractional and negative priority",
+			ops:  []PodOperation{{ID: 1, Priority: -1}, {ID: 2, Priority: 0.5}, {ID: 3, Priority: 0}, {ID: 4, Priority: 0.5}},
+			want: []int64{2, 4, 3, 1},
+		},
+		{
+			name: "empty",
+			ops:  nil,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SortOperations(tt.ops)
+			got := make([]int64, 0, len(tt.ops))
+			for _, po := range tt.ops {
+				got = append(got, po.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Status
+		want []string
+	}{
+		{
+			name: "initial generation",
+			s:    Status{ID: 5, X: true, Z: true},
+			want: []string{"X-5", "Z-5"},
+		},
+		{
+			name: "surged pods",
+			s:    Status{ID: 5, X: true, Y: true, Gen: [3]int{2, 0, 1}},
+			want: []string{"X-5-g2", "Y-5"},
+		},
+		{
+			name: "no enabled pods",
+			s:    Status{ID: 5, Gen: [3]int{1, 1, 1}},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := DeleteOperations(nil, &tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(ops))
+			for _, po := range ops {
+				if po.Code != OpCodeDelete {
+					t.Errorf("%s: code = %s, want %s", po.PodID, po.Code, OpCodeDelete)
+				}
+				got = append(got, po.PodID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pods = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestartOperationsSurge(t *testing.T) {
+	n, err := NewPodNamer("{{.Env}}-{{.Client.Name}}-{{.Type}}-{{.Client.ID}}", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Status{ID: 5, ClientID: 42, ClientName: "acme", X: true, Y: true, RestartStrategy: RestartSurge}
+
+	type restart struct {
+		pod  string
+		prev string
+	}
+	steps := [][]restart{
+		{{"prod-acme-x-42-g1", "prod-acme-x-42"}, {"prod-acme-y-42-g1", "prod-acme-y-42"}},
+		{{"prod-acme-x-42-g2", "prod-acme-x-42-g1"}, {"prod-acme-y-42-g2", "prod-acme-y-42-g1"}},
+	}
+
+	for i, want := range steps {
+		ops, err := RestartOperations(n, s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ops) != len(want) {
+			t.Fatalf("restart %d: got %d operations, want %d", i+1, len(ops), len(want))
+		}
+		for j, po := range ops {
+			if po.Code != OpCodeRestart || po.PodID != want[j].pod || po.PrevPodID != want[j].prev || po.ClientID != 42 {
+				t.Errorf("restart %d: operation = %+v, want %s -> %s", i+1, po, want[j].prev, want[j].pod)
+			}
+			ref, ok := n.Parse(po.PodID)
+			if !ok || ref.Generation != i+1 || ref.ClientID != 42 {
+				t.Errorf("restart %d: Parse(%q) = %+v, %v", i+1, po.PodID, ref, ok)
+			}
+		}
+	}
+
+	if s.Gen != [3]int{2, 2, 0} {
+		t.Errorf("generations = %v, want [2 2 0]", s.Gen)
+	}
+
+	// Удаление затрагивает поды последнего поколения
+	ops, err := DeleteOperations(n, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].PodID != "prod-acme-x-42-g2" || ops[1].PodID != "prod-acme-y-42-g2" {
+		t.Errorf("delete operations = %+v", ops)
+	}
+}
+
+func TestRestartOperationsRecreate(t *testing.T) {
+	s := &Status{ID: 5, X: true, Y: true, Gen: [3]int{1, 0, 0}, RestartStrategy: RestartRecreate}
+
+	ops, err := RestartOperations(nil, s, []string{PodX, PodZ})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].PodID != "X-5-g1" || ops[0].PrevPodID != "" || ops[0].Code != OpCodeRestart {
+		t.Errorf("operations = %+v", ops)
+	}
+	if s.Gen != [3]int{1, 0, 0} {
+		t.Errorf("generations = %v, want [1 0 0]", s.Gen)
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import "slices"
+
+// RestartFilter отбирает клиентов, поды которых требуется перезапустить.
+// Пустые поля не ограничивают выборку.
+type RestartFilter struct {
+	Image     string
+	Version   int
+	ClientIDs []int
+	Types     []string
+}
+
+// IsEmpty сообщает, не задано ли ни одного условия отбора.
+func (f *RestartFilter) IsEmpty() bool {
+	return f.Image == "" && f.Version == 0 && len(f.ClientIDs) == 0 && len(f.Types) == 0
+}
+
+// RestartOperations возвращает операции перезапуска включённых подов статуса.
+// Если types заданы, перезапускаются только поды указанных типов.
+// Перезапуск surge увеличивает поколение пода в s.
+func RestartOperations(n *PodNamer, s *Status, types []string) ([]PodOperation, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	ops := make([]PodOperation, 0, len(PodTypes))
+	for _, podType := range PodTypes {
+		if !s.IsOn(podType) || (len(types) > 0 && !slices.Contains(types, podType)) {
+			continue
+		}
+		podID, err := n.Name(podType, s)
+		if err != nil {
+			return nil, err
+		}
+		po, err := restartOperation(n, s, podType, podID)
+		if err != nil {
+			return nil, err
+		}
+		po.ClientID = s.ClientID
+		ops = append(ops, po)
+	}
+
+	return ops, nil
+}
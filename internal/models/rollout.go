@@ -0,0 +1,163 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidRollout = errors.New("invalid rollout")
+
+// Состояния выката
+const (
+	RolloutRunning   = "running"
+	RolloutCompleted = "completed"
+	RolloutHalted    = "halted"
+	RolloutCancelled = "cancelled"
+)
+
+// RolloutStep описывает очередной шаг выполняющегося выката.
+type RolloutStep int
+
+const (
+	// Ожидание завершения операций волны или окончания паузы
+	RolloutWait RolloutStep = iota
+	// Операции волны завершены, начинается пауза перед следующей волной
+	RolloutPause
+	// Начало следующей волны
+	RolloutNextWave
+	// Все клиенты обновлены, операции последней волны завершены
+	RolloutComplete
+	// Доля неудачных операций волны превышена
+	RolloutHalt
+)
+
+// RolloutSelector отбирает клиентов выката.
+// Пустые поля не ограничивают выборку.
+type RolloutSelector struct {
+	Image     string
+	Version   int
+	ClientIDs []int
+}
+
+// IsEmpty сообщает, не задано ли ни одного условия отбора.
+func (f *RolloutSelector) IsEmpty() bool {
+	return f.Image == "" && f.Version == 0 && len(f.ClientIDs) == 0
+}
+
+// Rollout описывает поэтапный выкат образа и версии на клиентов,
+// отобранных Selector при создании выката.
+//
+// Клиенты обновляются волнами по BatchSize клиентов, поды обновлённых
+// клиентов перезапускаются. Следующая волна начинается не ранее чем через
+// Pause после завершения операций предыдущей. Выкат останавливается,
+// если доля неудачных операций волны превышает MaxFailureRate.
+type Rollout struct {
+	ID       int64
+	Image    string
+	Version  int
+	Selector RolloutSelector
+
+	BatchSize      int
+	Pause          time.Duration
+	MaxFailureRate float64
+
+	State  string
+	Reason string // Причина остановки выката
+
+	// Номер текущей волны, 0 — ни одна волна не начата
+	Wave int
+
+	// Количество клиентов выката и клиентов, обновлённых начатыми волнами
+	Clients int
+	Updated int
+
+	// Статистика операций текущей волны
+	Stats WaveStats
+
+	WaveStartedAt *time.Time
+	NextWaveAt    *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// WaveStats описывает операции волны выката.
+type WaveStats struct {
+	Total   int // Зарегистрировано операций
+	Failed  int // Операций, хотя бы одна попытка которых неудачна
+	Pending int // Операций, ожидающих выполнения или выполняемых
+}
+
+// FailureRate возвращает долю неудачных операций волны.
+func (s WaveStats) FailureRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Total)
+}
+
+// Done сообщает, завершены ли операции волны.
+func (s WaveStats) Done() bool {
+	return s.Pending == 0
+}
+
+// Validate проверяет корректность параметров выката.
+func (r *Rollout) Validate() error {
+	if r.Selector.IsEmpty() {
+		return fmt.Errorf("%w: empty selector", ErrInvalidRollout)
+	}
+	if r.BatchSize <= 0 {
+		return fmt.Errorf("%w: batch size must be positive", ErrInvalidRollout)
+	}
+	if r.Pause < 0 {
+		return fmt.Errorf("%w: pause must not be negative", ErrInvalidRollout)
+	}
+	if r.MaxFailureRate < 0 || r.MaxFailureRate > 1 {
+		return fmt.Errorf("%w: max failure rate must be between 0 and 1", ErrInvalidRollout)
+	}
+	return nil
+}
+
+// Exceeded сообщает, превышена ли допустимая доля неудачных операций волны.
+func (r *Rollout) Exceeded(s WaveStats) bool {
+	return s.FailureRate() > r.MaxFailureRate
+}
+
+// Step возвращает очередной шаг выполняющегося выката в момент now.
+// Превышение доли неудачных операций останавливает выкат,
+// не дожидаясь завершения остальных операций волны.
+func (r *Rollout) Step(now time.Time) RolloutStep {
+	if r.Wave > 0 {
+		if r.Exceeded(r.Stats) {
+			return RolloutHalt
+		}
+		if !r.Stats.Done() {
+			return RolloutWait
+		}
+	}
+	if r.Updated >= r.Clients {
+		return RolloutComplete
+	}
+	if r.Wave == 0 || r.Pause == 0 {
+		return RolloutNextWave
+	}
+	if r.NextWaveAt == nil {
+		return RolloutPause
+	}
+	if now.Before(*r.NextWaveAt) {
+		return RolloutWait
+	}
+	return RolloutNextWave
+}
+
+// HaltReason возвращает причину остановки выката по статистике волны.
+func (r *Rollout) HaltReason() string {
+	return fmt.Sprintf("wave %d: %d of %d operations failed, max failure rate %g",
+		r.Wave, r.Stats.Failed, r.Stats.Total, r.MaxFailureRate)
+}
+
+// WaveRequestID возвращает идентификатор запроса, которым помечаются
+// операции волны выката.
+func (r *Rollout) WaveRequestID(wave int) string {
+	return fmt.Sprintf("rollout-%d-wave-%d", r.ID, wave)
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaveStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats WaveStats
+		rate  float64
+		done  bool
+	}{
+		{"empty", WaveStats{}, 0, true},
+		{"pending", WaveStats{Total: 4, Pending: 4}, 0, false},
+		{"partially failed", WaveStats{Total: 4, Failed: 1, Pending: 2}, 0.25, false},
+		{"all failed", WaveStats{Total: 4, Failed: 4}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.FailureRate(); got != tt.rate {
+				t.Errorf("FailureRate() = %v, want %v", got, tt.rate)
+			}
+			if got := tt.stats.Done(); got != tt.done {
+				t.Errorf("Done() = %v, want %v", got, tt.done)
+			}
+		})
+	}
+}
+
+func TestRolloutExceeded(t *testing.T) {
+	tests := []struct {
+		name  string
+		max   float64
+		stats WaveStats
+		want  bool
+	}{
+		{"no operations", 0, WaveStats{}, false},
+		{"no failures allowed", 0, WaveStats{Total: 10, Failed: 1}, true},
+		{"below limit", 0.2, WaveStats{Total: 10, Failed: 1}, false},
+		{"at limit", 0.2, WaveStats{Total: 10, Failed: 2}, false},
+		{"above limit", 0.2, WaveStats{Total: 10, Failed: 3}, true},
+		{"any failures allowed", 1, WaveStats{Total: 10, Failed: 10}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Rollout{MaxFailureRate: tt.max}
+			if got := r.Exceeded(tt.stats); got != tt.want {
+				t.Errorf("Exceeded(%+v) = %v, want %v", tt.stats, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloutStep(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Second)
+	after := now.Add(time.Second)
+
+	tests := []struct {
+		name string
+		r    Rollout
+		want RolloutStep
+	}{
+		{
+			name: "first wave",
+			r:    Rollout{Clients: 5, Pause: time.Minute},
+			want: RolloutNextWave,
+		},
+		{
+			name: "no clients",
+			r:    Rollout{Clients: 0},
+			want: RolloutComplete,
+		},
+		{
+			name: "wave in progress",
+			r:    Rollout{Wave: 1, Clients: 5, Updated: 2, Stats: WaveStats{Total: 4, Pending: 3}},
+			want: RolloutWait,
+		},
+		{
+			name: "failures exceeded before wave is done",
+			r: Rollout{
+				Wave: 1, Clients: 5, Updated: 2, MaxFailureRate: 0.25,
+				Stats: WaveStats{Total: 4, Failed: 2, Pending: 2},
+			},
+			want: RolloutHalt,
+		},
+		{
+			name: "failures within limit",
+			r: Rollout{
+				Wave: 1, Clients: 5, Updated: 2, MaxFailureRate: 0.25,
+				Stats: WaveStats{Total: 4, Failed: 1},
+			},
+			want: RolloutNextWave,
+		},
+		{
+			name: "failures exceeded in last wave",
+			r: Rollout{
+				Wave: 3, Clients: 5, Updated: 5,
+				Stats: WaveStats{Total: 2, Failed: 1},
+			},
+			want: RolloutHalt,
+		},
+		{
+			name: "wave done without pause",
+			r:    Rollout{Wave: 1, Clients: 5, Updated: 2, Stats: WaveStats{Total: 4}},
+			want: RolloutNextWave,
+		},
+		{
+			name: "wave done, pause not started",
+			r:    Rollout{Wave: 1, Clients: 5, Updated: 2, Pause: time.Minute, Stats: WaveStats{Total: 4}},
+			want: RolloutPause,
+		},
+		{
+			name: "pause in progress",
+			r: Rollout{
+				Wave: 1, Clients: 5, Updated: 2, Pause: time.Minute,
+				Stats: WaveStats{Total: 4}, NextWaveAt: &after,
+			},
+			want: RolloutWait,
+		},
+		{
+			name: "pause elapsed",
+			r: Rollout{
+				Wave: 1, Clients: 5, Updated: 2, Pause: time.Minute,
+				Stats: WaveStats{Total: 4}, NextWaveAt: &before,
+			},
+			want: RolloutNextWave,
+		},
+		{
+			name: "last wave in progress",
+			r:    Rollout{Wave: 3, Clients: 5, Updated: 5, Stats: WaveStats{Total: 2, Pending: 1}},
+			want: RolloutWait,
+		},
+		{
+			name: "last wave done",
+			r:    Rollout{Wave: 3, Clients: 5, Updated: 5, Pause: time.Minute, Stats: WaveStats{Total: 2}},
+			want: RolloutComplete,
+		},
+		{
+			name: "wave without operations",
+			r:    Rollout{Wave: 1, Clients: 5, Updated: 2},
+			want: RolloutNextWave,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.Step(now); got != tt.want {
+				t.Errorf("Step() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloutValidate(t *testing.T) {
+	valid := func() Rollout {
+		return Rollout{
+			Selector:       RolloutSelector{Image: "app"},
+			BatchSize:      2,
+			Pause:          time.Minute,
+			MaxFailureRate: 0.5,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *Rollout)
+		wantErr bool
+	}{
+		{"valid", func(r *Rollout) {}, false},
+		{"client selector", func(r *Rollout) { r.Selector = RolloutSelector{ClientIDs: []int{1}} }, false},
+		{"empty selector", func(r *Rollout) { r.Selector = RolloutSelector{} }, true},
+		{"zero batch size", func(r *Rollout) { r.BatchSize = 0 }, true},
+		{"zero pause", func(r *Rollout) { r.Pause = 0 }, false},
+		{"negative pause", func(r *Rollout) { r.Pause = -time.Second }, true},
+		{"zero failure rate", func(r *Rollout) { r.MaxFailureRate = 0 }, false},
+		{"full failure rate", func(r *Rollout) { r.MaxFailureRate = 1 }, false},
+		{"negative failure rate", func(r *Rollout) { r.MaxFailureRate = -0.1 }, true},
+		{"failure rate above one", func(r *Rollout) { r.MaxFailureRate = 1.1 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(&r)
+			err := r.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidRollout) {
+				t.Errorf("Validate() error = %v, want %v", err, ErrInvalidRollout)
+			}
+		})
+	}
+}
+
+func TestRolloutWaveRequestID(t *testing.T) {
+	r := &Rollout{ID: 7}
+	if got, want := r.WaveRequestID(3), "rollout-7-wave-3"; got != want {
+		t.Errorf("WaveRequestID(3) = %q, want %q", got, want)
+	}
+}
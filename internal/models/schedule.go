@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/cron"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Максимальная длительность окна расписания
+const MaxScheduleWindow = 7 * 24 * time.Hour
+
+// Schedule описывает запланированное изменение статуса.
+//
+// Разовое расписание (задано At) включает или выключает поды Types
+// в момент At. Повторяющееся расписание (задано Cron) включает поды Types
+// в моменты, соответствующие выражению cron в часовом поясе Timezone,
+// на время Duration, а по его истечении выключает.
+//
+// Расписание применяется только при смене требуемого состояния, поэтому
+// изменения, внесённые через API, сохраняются до следующей смены.
+type Schedule struct {
+	ID       int
+	StatusID int
+	Types    []string
+
+	// Разовое расписание
+	At time.Time
+	On bool
+
+	// Повторяющееся расписание
+	Cron     string
+	Duration time.Duration
+	Timezone string
+
+	// Последнее применённое состояние, nil — расписание не применялось
+	LastOn    *bool
+	AppliedAt *time.Time
+	CreatedAt time.Time
+}
+
+// IsWindow сообщает, является ли расписание повторяющимся.
+func (s *Schedule) IsWindow() bool {
+	return s.Cron != ""
+}
+
+// Validate проверяет корректность расписания.
+func (s *Schedule) Validate() error {
+	if len(s.Types) == 0 {
+		return fmt.Errorf("%w: no pod types", ErrInvalidSchedule)
+	}
+	for _, podType := range s.Types {
+		if !slices.Contains(PodTypes[:], podType) {
+			return fmt.Errorf("%w: unknown pod type %q", ErrInvalidSchedule, podType)
+		}
+	}
+
+	if !s.IsWindow() {
+		if s.At.IsZero() {
+			return fmt.Errorf("%w: either time or cron expression is required", ErrInvalidSchedule)
+		}
+		return nil
+	}
+
+	if !s.At.IsZero() {
+		return fmt.Errorf("%w: time and cron expression are mutually exclusive", ErrInvalidSchedule)
+	}
+	if _, err := cron.Parse(s.Cron); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+	if s.Duration < time.Minute || s.Duration > MaxScheduleWindow {
+		return fmt.Errorf("%w: duration must be between 1m and %s", ErrInvalidSchedule, MaxScheduleWindow)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+	return nil
+}
+
+// Due возвращает состояние подов, требуемое расписанием в момент now,
+// и сообщает, требуется ли его применить.
+func (s *Schedule) Due(now time.Time) (on bool, due bool, err error) {
+	if !s.IsWindow() {
+		if s.AppliedAt != nil || now.Before(s.At) {
+			return false, false, nil
+		}
+		return s.On, true, nil
+	}
+
+	expr, err := cron.Parse(s.Cron)
+	if err != nil {
+		return false, false, fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false, false, fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+
+	// Окно открыто, если последнее совпадение с выражением
+	// произошло менее Duration назад
+	start, ok := expr.Prev(now.In(loc), s.Duration)
+	on = ok && now.Before(start.Add(s.Duration))
+
+	return on, s.LastOn == nil || *s.LastOn != on, nil
+}
+
+// Apply возвращает статус, в котором поды расписания включены или выключены.
+func (s *Schedule) Apply(st Status, on bool) Status {
+	for _, podType := range s.Types {
+		st.SetOn(podType, on)
+	}
+	return st
+}
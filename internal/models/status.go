@@ -1,12 +1,38 @@
 package models
 
-import "log/slog"
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Типы подов
+const (
+	PodX = "X"
+	PodY = "Y"
+	PodZ = "Z"
+)
+
+var PodTypes = [...]string{PodX, PodY, PodZ}
 
 type Status struct {
 	ID int
 	X  bool
 	Y  bool
 	Z  bool
+
+	// Клиент статуса, используется для именования подов
+	ClientID   int
+	ClientName string
+
+	// Поколения подов в порядке PodTypes. Под с ненулевым поколением
+	// создан перезапуском surge и именуется с суффиксом поколения
+	Gen [len(PodTypes)]int
+
+	// Стратегия перезапуска подов клиента
+	RestartStrategy string
 }
 
 func (s *Status) LogValue() slog.Value {
@@ -15,3 +41,79 @@ func (s *Status) LogValue() slog.Value {
 	}
 	return slog.IntValue(s.ID)
 }
+
+// IsOn сообщает, должен ли быть запущен под заданного типа.
+func (s *Status) IsOn(podType string) bool {
+	if s == nil {
+		return false
+	}
+	switch podType {
+	case PodX:
+		return s.X
+	case PodY:
+		return s.Y
+	case PodZ:
+		return s.Z
+	default:
+		return false
+	}
+}
+
+// EnabledPods возвращает количество включённых подов статуса.
+func (s *Status) EnabledPods() int {
+	n := 0
+	for _, podType := range PodTypes {
+		if s.IsOn(podType) {
+			n++
+		}
+	}
+	return n
+}
+
+// Generation возвращает поколение пода заданного типа.
+func (s *Status) Generation(podType string) int {
+	if i := slices.Index(PodTypes[:], podType); i >= 0 {
+		return s.Gen[i]
+	}
+	return 0
+}
+
+// SetGeneration устанавливает поколение пода заданного типа.
+func (s *Status) SetGeneration(podType string, gen int) {
+	if i := slices.Index(PodTypes[:], podType); i >= 0 {
+		s.Gen[i] = gen
+	}
+}
+
+// SetOn включает или выключает под заданного типа.
+func (s *Status) SetOn(podType string, on bool) {
+	switch podType {
+	case PodX:
+		s.X = on
+	case PodY:
+		s.Y = on
+	case PodZ:
+		s.Z = on
+	}
+}
+
+// PodID возвращает имя пода заданного типа.
+func PodID(podType string, statusID int) string {
+	return fmt.Sprintf("%s-%d", podType, statusID)
+}
+
+// parsePodID разбирает имя пода, возвращая тип пода и идентификатор статуса.
+// Возвращает false, если имя не соответствует правилу именования PodID.
+func parsePodID(podID string) (podType string, statusID int, ok bool) {
+	podType, id, found := strings.Cut(podID, "-")
+	if !found || !slices.Contains(PodTypes[:], podType) {
+		return "", 0, false
+	}
+
+	statusID, err := strconv.Atoi(id)
+	if err != nil || statusID <= 0 || PodID(podType, statusID) != podID {
+		return "", 0, false
+	}
+
+	return podType, statusID, true
+}
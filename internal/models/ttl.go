@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// PodTTL задаёт время, по истечении которого включённый под выключается,
+// если его не продлили. 0 — без ограничений.
+//
+// Время жизни, заданное для статуса, действует вместо значений PodTTL
+// для всех его подов.
+type PodTTL struct {
+	X time.Duration
+	Y time.Duration
+	Z time.Duration
+}
+
+// Expired возвращает статус, в котором выключены поды заданных типов.
+func Expired(s Status, types []string) Status {
+	for _, podType := range types {
+		s.SetOn(podType, false)
+	}
+	return s
+}
@@ -11,15 +11,15 @@ import (
 	"github.com/korikhin/pod-sync/internal/lib/api"
 	httplib "github.com/korikhin/pod-sync/internal/lib/http"
 	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
 	"github.com/korikhin/pod-sync/internal/server"
 	"github.com/korikhin/pod-sync/internal/server/middleware/request"
-	"github.com/korikhin/pod-sync/internal/watcher"
 )
 
 var validator = api.NewValidator()
 
 // Add создаёт нового клиента и первоначальный статус.
-func Add(log *slog.Logger, s server.Storage, wa *watcher.Watcher) http.Handler {
+func Add(log *slog.Logger, s server.Storage) http.Handler {
 	log = log.With(sl.Component("api/clients"))
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -52,6 +52,11 @@ func Add(log *slog.Logger, s server.Storage, wa *watcher.Watcher) http.Handler {
 
 		_ /* client */, err := s.AddClient(context.Background(), p)
 		if err != nil {
+			if errors.Is(err, models.ErrInvalidPodName) {
+				log.Warn("could not create a client", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrInvalidPodName, http.StatusBadRequest)
+				return
+			}
 			log.Error("failed to create a client", sl.Error(err))
 			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
 			return
@@ -10,18 +10,16 @@ import (
 	"github.com/korikhin/pod-sync/internal/lib/api"
 	httplib "github.com/korikhin/pod-sync/internal/lib/http"
 	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
-	"github.com/korikhin/pod-sync/internal/models"
 	"github.com/korikhin/pod-sync/internal/server"
 	"github.com/korikhin/pod-sync/internal/server/middleware/request"
 	"github.com/korikhin/pod-sync/internal/storage"
-	"github.com/korikhin/pod-sync/internal/watcher"
 
 	"github.com/gorilla/mux"
 )
 
 // Delete удаляет клиента и соответствующий статус.
 // Регистрирует операции по удалению активных подов.
-func Delete(log *slog.Logger, s server.Storage, wa *watcher.Watcher) http.Handler {
+func Delete(log *slog.Logger, s server.Storage) http.Handler {
 	log = log.With(sl.Component("api/clients"))
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -39,7 +37,10 @@ func Delete(log *slog.Logger, s server.Storage, wa *watcher.Watcher) http.Handle
 			return
 		}
 
-		status, err := s.DeleteClient(context.Background(), id)
+		// Идентификатор запроса сохраняется в зарегистрированных операциях
+		ctx := context.WithoutCancel(r.Context())
+
+		status, err := s.DeleteClient(ctx, id)
 		if err != nil {
 			if errors.Is(err, storage.ErrClientNotFound) {
 				log.Warn("could not delete client", sl.Error(err))
@@ -54,9 +55,6 @@ func Delete(log *slog.Logger, s server.Storage, wa *watcher.Watcher) http.Handle
 			log.Warn("client deleted, but status not found")
 		}
 
-		ops := models.DeleteOperations(status)
-		wa.QueueOperations(ops)
-
 		httplib.ResponseJSON(w, api.OK(""), http.StatusNoContent)
 	}
 
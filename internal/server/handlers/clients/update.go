@@ -12,17 +12,17 @@ import (
 	"github.com/korikhin/pod-sync/internal/lib/api"
 	httplib "github.com/korikhin/pod-sync/internal/lib/http"
 	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
 	"github.com/korikhin/pod-sync/internal/server"
 	"github.com/korikhin/pod-sync/internal/server/middleware/request"
 	"github.com/korikhin/pod-sync/internal/storage"
-	"github.com/korikhin/pod-sync/internal/watcher"
 
 	"github.com/gorilla/mux"
 )
 
 // Update оновляет данные клиента.
 // Регистрирует операции по перезагрузке активных подов, если необходимо.
-func Update(log *slog.Logger, s server.Storage, wa *watcher.Watcher) http.Handler {
+func Update(log *slog.Logger, s server.Storage) http.Handler {
 	log = log.With(sl.Component("api/clients"))
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -60,18 +60,46 @@ func Update(log *slog.Logger, s server.Storage, wa *watcher.Watcher) http.Handle
 			return
 		}
 
-		if err := s.UpdateClient(context.Background(), clientID, p); err != nil {
+		// Идентификатор запроса сохраняется в зарегистрированных операциях
+		ctx := context.WithoutCancel(r.Context())
+
+		ops, err := s.UpdateClient(ctx, clientID, p)
+		if err != nil {
 			if errors.Is(err, storage.ErrClientNotFound) {
 				log.Warn("could not update client", sl.Error(err))
 				httplib.ResponseJSON(w, api.ErrClientNotFound, http.StatusNotFound)
 				return
 			}
+			if errors.Is(err, models.ErrInvalidPodName) {
+				log.Warn("could not update client", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrInvalidPodName, http.StatusBadRequest)
+				return
+			}
 			log.Error("failed to update client", sl.Error(err))
 			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
 			return
 		}
 
-		httplib.ResponseJSON(w, api.OK("client updated successfully"), http.StatusOK)
+		resp := api.OK("client updated successfully")
+		if len(ops) == 0 {
+			httplib.ResponseJSON(w, resp, http.StatusOK)
+			return
+		}
+
+		log.Info("restart queued", slog.Int("operations", len(ops)))
+
+		queued := make([]api.Operation, 0, len(ops))
+		for _, po := range ops {
+			queued = append(queued, api.Operation{
+				ID:            po.ID,
+				PodID:         po.PodID,
+				PreviousPodID: po.PrevPodID,
+				Operation:     po.Code.String(),
+			})
+		}
+
+		resp.Data = queued
+		httplib.ResponseJSON(w, resp, http.StatusOK)
 	}
 
 	return http.HandlerFunc(handler)
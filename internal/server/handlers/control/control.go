@@ -0,0 +1,64 @@
+package control
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/watcher"
+)
+
+// Pause приостанавливает выполнение операций с подами.
+// Изменения статусов продолжают приниматься и заносятся в очередь.
+func Pause(log *slog.Logger, wa *watcher.Watcher) http.Handler {
+	log = log.With(sl.Component("api/control"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.control.Pause"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		if err := wa.Pause(context.Background()); err != nil {
+			log.Error("failed to pause watcher", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		log.Warn("watcher paused")
+		httplib.ResponseJSON(w, api.OK("watcher paused"), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// Resume возобновляет выполнение операций с подами.
+func Resume(log *slog.Logger, wa *watcher.Watcher) http.Handler {
+	log = log.With(sl.Component("api/control"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.control.Resume"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		if err := wa.Resume(context.Background()); err != nil {
+			log.Error("failed to resume watcher", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("watcher resumed")
+		httplib.ResponseJSON(w, api.OK("watcher resumed"), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
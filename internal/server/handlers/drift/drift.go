@@ -0,0 +1,54 @@
+package drift
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/watcher"
+)
+
+// Get возвращает расхождение между статусами и запущенными подами.
+func Get(log *slog.Logger, wa *watcher.Watcher) http.Handler {
+	log = log.With(sl.Component("api/drift"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.drift.Get"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		d, err := wa.Drift(context.Background())
+		if err != nil {
+			log.Error("failed to detect drift", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		resp := api.Drift{
+			Missing:    podIDs(d.Missing),
+			Unexpected: podIDs(d.Unexpected),
+			Stale:      podIDs(d.Stale),
+			Orphans:    d.Orphans,
+		}
+
+		httplib.ResponseJSON(w, api.Data(resp), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+func podIDs(pods []models.Pod) []string {
+	ids := make([]string, 0, len(pods))
+	for _, p := range pods {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
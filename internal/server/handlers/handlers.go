@@ -6,11 +6,21 @@ import (
 
 	"github.com/korikhin/pod-sync/internal/server"
 	"github.com/korikhin/pod-sync/internal/server/handlers/clients"
+	"github.com/korikhin/pod-sync/internal/server/handlers/control"
+	"github.com/korikhin/pod-sync/internal/server/handlers/drift"
 	"github.com/korikhin/pod-sync/internal/server/handlers/health"
+	"github.com/korikhin/pod-sync/internal/server/handlers/operations"
+	"github.com/korikhin/pod-sync/internal/server/handlers/preemptions"
+	"github.com/korikhin/pod-sync/internal/server/handlers/reconcile"
+	"github.com/korikhin/pod-sync/internal/server/handlers/restart"
+	"github.com/korikhin/pod-sync/internal/server/handlers/rollouts"
+	"github.com/korikhin/pod-sync/internal/server/handlers/schedules"
 	"github.com/korikhin/pod-sync/internal/server/handlers/status"
 	"github.com/korikhin/pod-sync/internal/server/middleware/request"
 	"github.com/korikhin/pod-sync/internal/watcher"
 
+	"github.com/korikhin/pod-sync/pkg/deployer/breaker"
+
 	"github.com/gorilla/mux"
 )
 
@@ -18,25 +28,95 @@ func NewRouter() *mux.Router {
 	return mux.NewRouter().PathPrefix("/api").Subrouter()
 }
 
-func RegisterHandlers(r *mux.Router, log *slog.Logger, s server.Storage, w *watcher.Watcher) {
+func RegisterHandlers(r *mux.Router, log *slog.Logger, s server.Storage, w *watcher.Watcher, b *breaker.Breaker) {
 	nonEmpty := request.NonEmpty(log)
 
 	// Health
-	health := health.New(log)
+	health := health.New(log, w, b)
 	r.Handle("/health", health).Methods(http.MethodGet)
 
 	// Clients
-	addClient := clients.Add(log, s, w)
+	addClient := clients.Add(log, s)
 	r.Handle("/v1/clients", nonEmpty(addClient)).Methods(http.MethodPost)
 
-	updateClient := clients.Update(log, s, w)
+	updateClient := clients.Update(log, s)
 	r.Handle("/v1/clients/{id:[0-9]+}", nonEmpty(updateClient)).Methods(http.MethodPut)
 
-	deleteClient := clients.Delete(log, s, w)
+	deleteClient := clients.Delete(log, s)
 	r.Handle("/v1/clients/{id:[0-9]+}", deleteClient).Methods(http.MethodDelete)
 
+	syncClient := reconcile.Client(log, w)
+	r.Handle("/v1/clients/{id:[0-9]+}/sync", syncClient).Methods(http.MethodPost)
+
 	// Status
-	updateStatus := status.Update(log, s, w)
+	updateStatus := status.Update(log, s)
 	r.Handle("/v1/status/{id:[0-9]+}", nonEmpty(updateStatus)).Methods(http.MethodPut)
 
+	renewStatus := status.Renew(log, s)
+	r.Handle("/v1/status/{id:[0-9]+}/renew", renewStatus).Methods(http.MethodPost)
+
+	setStatusTTL := status.SetTTL(log, s)
+	r.Handle("/v1/status/{id:[0-9]+}/ttl", nonEmpty(setStatusTTL)).Methods(http.MethodPut)
+
+	// Schedules
+	addSchedule := schedules.Add(log, s)
+	r.Handle("/v1/status/{id:[0-9]+}/schedules", nonEmpty(addSchedule)).Methods(http.MethodPost)
+
+	statusSchedules := schedules.List(log, s)
+	r.Handle("/v1/status/{id:[0-9]+}/schedules", statusSchedules).Methods(http.MethodGet)
+
+	deleteSchedule := schedules.Delete(log, s)
+	r.Handle("/v1/schedules/{id:[0-9]+}", deleteSchedule).Methods(http.MethodDelete)
+
+	// Restart
+	restartPods := restart.Pods(log, s)
+	r.Handle("/v1/restart", nonEmpty(restartPods)).Methods(http.MethodPost)
+
+	// Rollouts
+	createRollout := rollouts.Create(log, s)
+	r.Handle("/v1/rollouts", nonEmpty(createRollout)).Methods(http.MethodPost)
+
+	listRollouts := rollouts.List(log, s)
+	r.Handle("/v1/rollouts", listRollouts).Methods(http.MethodGet)
+
+	getRollout := rollouts.Get(log, s)
+	r.Handle("/v1/rollouts/{id:[0-9]+}", getRollout).Methods(http.MethodGet)
+
+	cancelRollout := rollouts.Cancel(log, s)
+	r.Handle("/v1/rollouts/{id:[0-9]+}/cancel", cancelRollout).Methods(http.MethodPost)
+
+	// Preemptions
+	listPreemptions := preemptions.List(log, s)
+	r.Handle("/v1/preemptions", listPreemptions).Methods(http.MethodGet)
+
+	// Sync
+	syncAll := reconcile.All(log, w)
+	r.Handle("/v1/sync", syncAll).Methods(http.MethodPost)
+
+	// Watcher
+	pause := control.Pause(log, w)
+	r.Handle("/v1/watcher/pause", pause).Methods(http.MethodPost)
+
+	resume := control.Resume(log, w)
+	r.Handle("/v1/watcher/resume", resume).Methods(http.MethodPost)
+
+	// Drift
+	getDrift := drift.Get(log, w)
+	r.Handle("/v1/drift", getDrift).Methods(http.MethodGet)
+
+	// Operations
+	operationLog := operations.List(log, s)
+	r.Handle("/v1/operations", operationLog).Methods(http.MethodGet)
+
+	getOperation := operations.Get(log, s)
+	r.Handle("/v1/operations/{id:[0-9]+}", getOperation).Methods(http.MethodGet)
+
+	deadLetters := operations.DeadLetters(log, s)
+	r.Handle("/v1/operations/dead", deadLetters).Methods(http.MethodGet)
+
+	retryDeadLetters := operations.RetryDeadLetters(log, s)
+	r.Handle("/v1/operations/dead/retry", retryDeadLetters).Methods(http.MethodPost)
+
+	retryDeadLetter := operations.RetryDeadLetter(log, s)
+	r.Handle("/v1/operations/dead/{id:[0-9]+}/retry", retryDeadLetter).Methods(http.MethodPost)
 }
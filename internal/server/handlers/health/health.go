@@ -1,16 +1,25 @@
 package health
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/korikhin/pod-sync/internal/lib/api"
 	httplib "github.com/korikhin/pod-sync/internal/lib/http"
 	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
 	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/watcher"
+
+	"github.com/korikhin/pod-sync/pkg/deployer/breaker"
 )
 
-func New(log *slog.Logger) http.Handler {
+// Максимальное время получения признака приостановки из хранилища.
+// По истечении возвращается последнее известное состояние.
+const pausedTimeout = time.Second
+
+func New(log *slog.Logger, wa *watcher.Watcher, b *breaker.Breaker) http.Handler {
 	log = log.With(sl.Component("api/health"))
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -18,8 +27,27 @@ func New(log *slog.Logger) http.Handler {
 			sl.RequestID(request.GetID(r.Context())),
 		)
 
+		ctx, cancel := context.WithTimeout(r.Context(), pausedTimeout)
+		defer cancel()
+
+		stats := wa.Stats()
+		bs := b.Stats()
+		resp := api.Health{
+			Deployer: api.DeployerStats{
+				Breaker:         string(bs.State),
+				BreakerFailures: bs.Failures,
+				BreakerOpened:   bs.Opened,
+				BreakerRejected: bs.Rejected,
+			},
+			Watcher: api.WatcherStats{
+				Leader:              wa.IsLeader(),
+				Paused:              wa.Paused(ctx),
+				OperationsCollapsed: stats.OperationsCollapsed,
+			},
+		}
+
 		log.Info("")
-		httplib.ResponseJSON(w, api.OK(""), http.StatusOK)
+		httplib.ResponseJSON(w, api.Data(resp), http.StatusOK)
 	}
 
 	return http.HandlerFunc(handler)
@@ -0,0 +1,124 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// DeadLetters возвращает операции, которые не удалось выполнить
+// за максимальное количество попыток.
+func DeadLetters(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/operations"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.operations.DeadLetters"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		items, err := s.GetDeadLetters(context.Background())
+		if err != nil {
+			log.Error("failed to get dead letters", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]api.DeadLetter, 0, len(items))
+		for _, d := range items {
+			resp = append(resp, deadLetter(d))
+		}
+
+		httplib.ResponseJSON(w, api.Data(resp), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// RetryDeadLetter возвращает операцию из списка dead letters в очередь.
+func RetryDeadLetter(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/operations"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.operations.RetryDeadLetter"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrDeadLetterNotFound, http.StatusNotFound)
+			return
+		}
+
+		if _, err := s.RetryDeadLetters(context.Background(), []int64{id}); err != nil {
+			if errors.Is(err, storage.ErrOperationNotFound) {
+				log.Warn("could not retry dead letter", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrDeadLetterNotFound, http.StatusNotFound)
+				return
+			}
+			log.Error("failed to retry dead letter", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		httplib.ResponseJSON(w, api.OK("operation queued successfully"), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// RetryDeadLetters возвращает все операции из списка dead letters в очередь.
+func RetryDeadLetters(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/operations"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.operations.RetryDeadLetters"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		n, err := s.RetryDeadLetters(context.Background(), nil)
+		if err != nil {
+			log.Error("failed to retry dead letters", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("dead letters queued", slog.Int("count", n))
+		httplib.ResponseJSON(w, api.OK(fmt.Sprintf("%d operations queued successfully", n)), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+func deadLetter(d models.DeadLetter) api.DeadLetter {
+	return api.DeadLetter{
+		ID:        d.Op.ID,
+		PodID:     d.Op.PodID,
+		Operation: d.Op.Code.String(),
+		Attempts:  d.Op.Attempts,
+		Error:     d.Error,
+		FailedAt:  d.FailedAt,
+	}
+}
@@ -0,0 +1,128 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	queryParamWait = "wait"
+
+	// Максимальное время ожидания завершения операции.
+	// Превышает время ожидания записи ответа сервера, поэтому оно продлевается.
+	maxWait = 5 * time.Minute
+
+	// Интервал опроса состояния операции при ожидании
+	pollInterval = 500 * time.Millisecond
+)
+
+// Get возвращает состояние операции.
+// Если задан параметр wait, ожидает завершения операции не дольше wait.
+func Get(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/operations"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.operations.Get"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrOperationNotFound, http.StatusNotFound)
+			return
+		}
+
+		var wait time.Duration
+		if v := r.URL.Query().Get(queryParamWait); v != "" {
+			wait, err = time.ParseDuration(v)
+			if err != nil || wait < 0 || wait > maxWait {
+				log.Warn("bad request", slog.String(queryParamWait, v))
+				msg := "wait must be a duration up to " + maxWait.String()
+				httplib.ResponseJSON(w, api.Error(msg), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if wait > 0 {
+			rc := http.NewResponseController(w)
+			if err := rc.SetWriteDeadline(time.Now().Add(wait + pollInterval)); err != nil {
+				log.Warn("failed to extend write deadline", sl.Error(err))
+			}
+		}
+
+		st, err := poll(r.Context(), s, id, wait)
+		if err != nil {
+			if errors.Is(err, storage.ErrOperationNotFound) {
+				log.Warn("could not get operation", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrOperationNotFound, http.StatusNotFound)
+				return
+			}
+			log.Error("failed to get operation", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		resp := api.OperationStatus{
+			ID:            st.Op.ID,
+			PodID:         st.Op.PodID,
+			PreviousPodID: st.Op.PrevPodID,
+			Operation:     st.Op.Code.String(),
+			State:         st.State,
+			Attempts:      st.Op.Attempts,
+			Error:         st.Error,
+			UpdatedAt:     st.UpdatedAt,
+		}
+
+		httplib.ResponseJSON(w, api.Data(resp), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// poll возвращает состояние операции, ожидая её завершения не дольше wait.
+// Если ожидание прервано отменой ctx, возвращает последнее полученное состояние.
+func poll(ctx context.Context, s server.Storage, id int64, wait time.Duration) (*models.OperationStatus, error) {
+	deadline := time.Now().Add(wait)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var last *models.OperationStatus
+	for {
+		st, err := s.GetOperation(ctx, id)
+		if err != nil {
+			if last != nil && ctx.Err() != nil {
+				return last, nil
+			}
+			return nil, err
+		}
+		if st.IsFinal() || !time.Now().Before(deadline) {
+			return st, nil
+		}
+		last = st
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return st, nil
+		}
+	}
+}
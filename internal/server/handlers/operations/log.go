@@ -0,0 +1,92 @@
+package operations
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+)
+
+const (
+	queryParamClientID = "client_id"
+	queryParamSince    = "since"
+)
+
+// List возвращает историю выполнения операций с подами.
+func List(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/operations"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.operations.List"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		query := r.URL.Query()
+
+		clientID := 0
+		if v := query.Get(queryParamClientID); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil || id <= 0 {
+				log.Warn("bad request", slog.String(queryParamClientID, v))
+				httplib.ResponseJSON(w, api.Error("client_id must be a positive integer"), http.StatusBadRequest)
+				return
+			}
+			clientID = id
+		}
+
+		var since time.Time
+		if v := query.Get(queryParamSince); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				log.Warn("bad request", sl.Error(err))
+				httplib.ResponseJSON(w, api.Error("since must be an RFC 3339 timestamp"), http.StatusBadRequest)
+				return
+			}
+			since = t
+		}
+
+		records, err := s.GetOperationLog(context.Background(), clientID, since)
+		if err != nil {
+			log.Error("failed to get operation log", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]api.OperationRecord, 0, len(records))
+		for _, rec := range records {
+			resp = append(resp, operationRecord(rec))
+		}
+
+		httplib.ResponseJSON(w, api.Data(resp), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+func operationRecord(r models.OperationRecord) api.OperationRecord {
+	return api.OperationRecord{
+		ID:            r.ID,
+		OperationID:   r.Op.ID,
+		PodID:         r.Op.PodID,
+		PreviousPodID: r.Op.PrevPodID,
+		Operation:     r.Op.Code.String(),
+		ClientID:      r.Op.ClientID,
+		RequestID:     r.Op.RequestID,
+		Attempt:       r.Attempt,
+		Result:        r.Result,
+		Error:         r.Error,
+		StartedAt:     r.StartedAt,
+		FinishedAt:    r.FinishedAt,
+	}
+}
@@ -0,0 +1,119 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/storage"
+	"github.com/korikhin/pod-sync/internal/watcher"
+
+	"github.com/gorilla/mux"
+)
+
+// Максимальное время ожидания синхронизации.
+// Превышает время ожидания записи ответа сервера, поэтому оно продлевается.
+const syncTimeout = 5 * time.Minute
+
+// All немедленно выполняет зарегистрированные операции и сверку всех подов.
+// Возвращает исходы выполненных операций.
+//
+// Синхронизацию выполняет только лидер, поэтому запрос должен быть направлен
+// лидеру: остальные экземпляры отвечают 503 и не сохраняют запрос.
+func All(log *slog.Logger, wa *watcher.Watcher) http.Handler {
+	log = log.With(sl.Component("api/reconcile"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.reconcile.All"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		serve(log, w, r, wa, 0)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// Client немедленно выполняет зарегистрированные операции и сверку подов клиента.
+// Возвращает исходы выполненных операций. Запрос должен быть направлен лидеру, см. All.
+func Client(log *slog.Logger, wa *watcher.Watcher) http.Handler {
+	log = log.With(sl.Component("api/reconcile"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.reconcile.Client"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		clientID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil || clientID == 0 {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrClientNotFound, http.StatusNotFound)
+			return
+		}
+
+		serve(log, w, r, wa, clientID)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+func serve(log *slog.Logger, w http.ResponseWriter, r *http.Request, wa *watcher.Watcher, clientID int) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(syncTimeout)); err != nil {
+		log.Warn("failed to extend write deadline", sl.Error(err))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), syncTimeout)
+	defer cancel()
+
+	results, err := wa.Sync(ctx, clientID)
+	if err != nil {
+		switch {
+		case errors.Is(err, watcher.ErrNotLeader):
+			log.Warn("could not sync", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrNotLeader, http.StatusServiceUnavailable)
+		case errors.Is(err, watcher.ErrPaused):
+			log.Warn("could not sync", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrPaused, http.StatusConflict)
+		case errors.Is(err, storage.ErrClientNotFound):
+			log.Warn("could not sync", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrClientNotFound, http.StatusNotFound)
+		case errors.Is(err, storage.ErrStatusNotFound):
+			log.Warn("could not sync", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrStatusNotFound, http.StatusNotFound)
+		default:
+			log.Error("failed to sync", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp := make([]api.OperationResult, 0, len(results))
+	for _, res := range results {
+		item := api.OperationResult{
+			PodID:     res.Op.PodID,
+			Operation: res.Op.Code.String(),
+			Outcome:   res.Outcome,
+		}
+		if res.Err != nil {
+			item.Error = res.Err.Error()
+		}
+		resp = append(resp, item)
+	}
+
+	log.Info("sync completed", slog.Int("operations", len(resp)))
+	httplib.ResponseJSON(w, api.Data(resp), http.StatusOK)
+}
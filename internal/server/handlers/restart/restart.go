@@ -0,0 +1,99 @@
+package restart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+)
+
+var validator = api.NewValidator()
+
+// Pods регистрирует перезапуск включённых подов клиентов, соответствующих фильтру.
+func Pods(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/restart"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.restart.Pods"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		p := api.RestartFilter{}
+		if err := httplib.DecodeJSON(r.Body, &p); err != nil {
+			var typeError *json.UnmarshalTypeError
+			if errors.As(err, &typeError) {
+				log.Warn("bad request", sl.Error(typeError))
+				msg := fmt.Sprintf("field %s must be type %s", typeError.Field, typeError.Type)
+				httplib.ResponseJSON(w, api.Error(msg), http.StatusBadRequest)
+				return
+			}
+			log.Error("failed to decode request body", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		if err := api.Validate(validator, p); err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.Error(err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		f := models.RestartFilter{
+			ClientIDs: p.ClientIDs,
+			Types:     p.Types,
+		}
+		if p.Image != nil {
+			f.Image = *p.Image
+		}
+		if p.Version != nil {
+			f.Version = *p.Version
+		}
+
+		// Перезапуск всех подов требует явного фильтра
+		if f.IsEmpty() {
+			log.Warn("bad request: empty filter")
+			httplib.ResponseJSON(w, api.Error("at least one filter field is required"), http.StatusBadRequest)
+			return
+		}
+
+		// Идентификатор запроса сохраняется в зарегистрированных операциях
+		ctx := context.WithoutCancel(r.Context())
+
+		ops, err := s.RestartPods(ctx, f)
+		if err != nil {
+			log.Error("failed to restart pods", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("restart queued", slog.Int("operations", len(ops)))
+
+		queued := make([]api.Operation, 0, len(ops))
+		for _, po := range ops {
+			queued = append(queued, api.Operation{
+				ID:            po.ID,
+				PodID:         po.PodID,
+				PreviousPodID: po.PrevPodID,
+				Operation:     po.Code.String(),
+			})
+		}
+
+		resp := api.OK(fmt.Sprintf("%d pods queued for restart", len(ops)))
+		resp.Data = queued
+		httplib.ResponseJSON(w, resp, http.StatusAccepted)
+	}
+
+	return http.HandlerFunc(handler)
+}
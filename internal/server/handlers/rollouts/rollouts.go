@@ -0,0 +1,267 @@
+package rollouts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+var validator = api.NewValidator()
+
+// Create создаёт поэтапный выкат образа и версии.
+// Волны выката выполняет лидер.
+func Create(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/rollouts"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rollouts.Create"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		p := api.Rollout{}
+		if err := httplib.DecodeJSON(r.Body, &p); err != nil {
+			var typeError *json.UnmarshalTypeError
+			if errors.As(err, &typeError) {
+				log.Warn("bad request", sl.Error(typeError))
+				msg := fmt.Sprintf("field %s must be type %s", typeError.Field, typeError.Type)
+				httplib.ResponseJSON(w, api.Error(msg), http.StatusBadRequest)
+				return
+			}
+			log.Error("failed to decode request body", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		if err := api.Validate(validator, p); err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.Error(err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		ro, err := rollout(p)
+		if err == nil {
+			err = ro.Validate()
+		}
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.Error(err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		created, err := s.CreateRollout(r.Context(), ro)
+		if err != nil {
+			if errors.Is(err, storage.ErrRolloutRunning) {
+				log.Warn("could not create rollout", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrRolloutRunning, http.StatusConflict)
+				return
+			}
+			log.Error("failed to create rollout", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("rollout created", slog.Int64("rollout_id", created.ID), slog.Int("clients", created.Clients))
+
+		resp := api.OK("rollout created successfully")
+		resp.Data = rolloutInfo(*created)
+		httplib.ResponseJSON(w, resp, http.StatusCreated)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// List возвращает выкаты.
+func List(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/rollouts"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rollouts.List"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		rollouts, err := s.GetRollouts(r.Context())
+		if err != nil {
+			log.Error("failed to get rollouts", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]api.RolloutInfo, 0, len(rollouts))
+		for _, ro := range rollouts {
+			resp = append(resp, rolloutInfo(ro))
+		}
+
+		httplib.ResponseJSON(w, api.Data(resp), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// Get возвращает выкат со статистикой текущей волны.
+func Get(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/rollouts"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rollouts.Get"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrRolloutNotFound, http.StatusNotFound)
+			return
+		}
+
+		ro, err := s.GetRollout(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, storage.ErrRolloutNotFound) {
+				log.Warn("could not get rollout", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrRolloutNotFound, http.StatusNotFound)
+				return
+			}
+			log.Error("failed to get rollout", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		httplib.ResponseJSON(w, api.Data(rolloutInfo(*ro)), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// Cancel отменяет выполняющийся выкат.
+// Клиенты, обновлённые начатыми волнами, не возвращаются к прежней версии.
+func Cancel(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/rollouts"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rollouts.Cancel"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrRolloutNotFound, http.StatusNotFound)
+			return
+		}
+
+		if err := s.CancelRollout(r.Context(), id); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrRolloutNotFound):
+				log.Warn("could not cancel rollout", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrRolloutNotFound, http.StatusNotFound)
+			case errors.Is(err, storage.ErrRolloutNotRunning):
+				log.Warn("could not cancel rollout", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrRolloutNotRunning, http.StatusConflict)
+			default:
+				log.Error("failed to cancel rollout", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		log.Info("rollout cancelled", slog.Int64("rollout_id", id))
+
+		httplib.ResponseJSON(w, api.OK("rollout cancelled"), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// rollout формирует выкат из тела запроса.
+func rollout(p api.Rollout) (models.Rollout, error) {
+	ro := models.Rollout{
+		Image:     *p.Image,
+		Version:   *p.Version,
+		BatchSize: *p.BatchSize,
+		Selector: models.RolloutSelector{
+			ClientIDs: p.Selector.ClientIDs,
+		},
+	}
+	if p.Selector.Image != nil {
+		ro.Selector.Image = *p.Selector.Image
+	}
+	if p.Selector.Version != nil {
+		ro.Selector.Version = *p.Selector.Version
+	}
+	if p.MaxFailureRate != nil {
+		ro.MaxFailureRate = *p.MaxFailureRate
+	}
+
+	if p.Pause != nil && *p.Pause != "" {
+		d, err := time.ParseDuration(*p.Pause)
+		if err != nil {
+			return ro, fmt.Errorf("field pause is not valid: %w", err)
+		}
+		ro.Pause = d
+	}
+	return ro, nil
+}
+
+func rolloutInfo(ro models.Rollout) api.RolloutInfo {
+	info := api.RolloutInfo{
+		ID:      ro.ID,
+		Image:   ro.Image,
+		Version: ro.Version,
+		Selector: api.RolloutSelector{
+			ClientIDs: ro.Selector.ClientIDs,
+		},
+		BatchSize:      ro.BatchSize,
+		Pause:          ro.Pause.String(),
+		MaxFailureRate: ro.MaxFailureRate,
+		State:          ro.State,
+		Reason:         ro.Reason,
+		Clients:        ro.Clients,
+		Updated:        ro.Updated,
+		NextWaveAt:     ro.NextWaveAt,
+		CreatedAt:      ro.CreatedAt,
+		UpdatedAt:      ro.UpdatedAt,
+	}
+	if ro.Selector.Image != "" {
+		info.Selector.Image = &ro.Selector.Image
+	}
+	if ro.Selector.Version != 0 {
+		info.Selector.Version = &ro.Selector.Version
+	}
+
+	if ro.Wave > 0 {
+		info.Wave = &api.RolloutWave{
+			Number:     ro.Wave,
+			RequestID:  ro.WaveRequestID(ro.Wave),
+			Operations: ro.Stats.Total,
+			Failed:     ro.Stats.Failed,
+			Pending:    ro.Stats.Pending,
+			StartedAt:  ro.WaveStartedAt,
+		}
+	}
+	return info
+}
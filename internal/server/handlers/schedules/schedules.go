@@ -0,0 +1,227 @@
+package schedules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+var validator = api.NewValidator()
+
+// Часовой пояс повторяющегося расписания по умолчанию
+const defaultTimezone = "UTC"
+
+// Add добавляет расписание статуса.
+func Add(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/schedules"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.schedules.Add"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		statusID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrStatusNotFound, http.StatusNotFound)
+			return
+		}
+
+		p := api.Schedule{}
+		if err := httplib.DecodeJSON(r.Body, &p); err != nil {
+			var typeError *json.UnmarshalTypeError
+			if errors.As(err, &typeError) {
+				log.Warn("bad request", sl.Error(typeError))
+				msg := fmt.Sprintf("field %s must be type %s", typeError.Field, typeError.Type)
+				httplib.ResponseJSON(w, api.Error(msg), http.StatusBadRequest)
+				return
+			}
+			var timeError *time.ParseError
+			if errors.As(err, &timeError) {
+				log.Warn("bad request", sl.Error(timeError))
+				httplib.ResponseJSON(w, api.Error("field at must be an RFC 3339 timestamp"), http.StatusBadRequest)
+				return
+			}
+			log.Error("failed to decode request body", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		if err := api.Validate(validator, p); err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.Error(err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		sc, err := schedule(statusID, p)
+		if err == nil {
+			err = sc.Validate()
+		}
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.Error(err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		created, err := s.AddSchedule(context.Background(), sc)
+		if err != nil {
+			if errors.Is(err, storage.ErrStatusNotFound) {
+				log.Warn("could not add schedule", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrStatusNotFound, http.StatusNotFound)
+				return
+			}
+			log.Error("failed to add schedule", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("schedule added", slog.Int("schedule_id", created.ID))
+
+		resp := api.OK("schedule added successfully")
+		resp.Data = scheduleInfo(*created)
+		httplib.ResponseJSON(w, resp, http.StatusCreated)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// List возвращает расписания статуса.
+func List(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/schedules"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.schedules.List"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		statusID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrStatusNotFound, http.StatusNotFound)
+			return
+		}
+
+		schedules, err := s.GetStatusSchedules(context.Background(), statusID)
+		if err != nil {
+			log.Error("failed to get schedules", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]api.ScheduleInfo, 0, len(schedules))
+		for _, sc := range schedules {
+			resp = append(resp, scheduleInfo(sc))
+		}
+
+		httplib.ResponseJSON(w, api.Data(resp), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// Delete удаляет расписание.
+// Состояние подов, установленное расписанием ранее, не изменяется.
+func Delete(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/schedules"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.schedules.Delete"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrScheduleNotFound, http.StatusNotFound)
+			return
+		}
+
+		if err := s.DeleteSchedule(context.Background(), id); err != nil {
+			if errors.Is(err, storage.ErrScheduleNotFound) {
+				log.Warn("could not delete schedule", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrScheduleNotFound, http.StatusNotFound)
+				return
+			}
+			log.Error("failed to delete schedule", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		httplib.ResponseJSON(w, api.OK(""), http.StatusNoContent)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// schedule формирует расписание из тела запроса.
+func schedule(statusID int, p api.Schedule) (models.Schedule, error) {
+	sc := models.Schedule{
+		StatusID: statusID,
+		Types:    p.Types,
+	}
+
+	if p.Cron == nil {
+		sc.At = p.At.UTC()
+		sc.On = *p.On
+		return sc, nil
+	}
+
+	d, err := time.ParseDuration(*p.Duration)
+	if err != nil {
+		return sc, fmt.Errorf("field duration is not valid: %w", err)
+	}
+
+	sc.Cron = *p.Cron
+	sc.Duration = d
+	sc.Timezone = defaultTimezone
+	if p.Timezone != nil {
+		sc.Timezone = *p.Timezone
+	}
+	return sc, nil
+}
+
+func scheduleInfo(sc models.Schedule) api.ScheduleInfo {
+	info := api.ScheduleInfo{
+		ID:        sc.ID,
+		StatusID:  sc.StatusID,
+		Types:     sc.Types,
+		LastOn:    sc.LastOn,
+		AppliedAt: sc.AppliedAt,
+		CreatedAt: sc.CreatedAt,
+	}
+
+	if sc.IsWindow() {
+		info.Cron = sc.Cron
+		info.Duration = sc.Duration.String()
+		info.Timezone = sc.Timezone
+		return info
+	}
+
+	at, on := sc.At, sc.On
+	info.At, info.On = &at, &on
+	return info
+}
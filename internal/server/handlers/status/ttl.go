@@ -0,0 +1,147 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/api"
+	httplib "github.com/korikhin/pod-sync/internal/lib/http"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/server"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// Renew продлевает время жизни включённых подов статуса.
+// Тело запроса необязательно; без него продлеваются все включённые поды.
+func Renew(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/status"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.status.Renew"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrStatusNotFound, http.StatusNotFound)
+			return
+		}
+
+		p := api.Renew{}
+		if err := httplib.DecodeJSON(r.Body, &p); err != nil && !errors.Is(err, io.EOF) {
+			var typeError *json.UnmarshalTypeError
+			if errors.As(err, &typeError) {
+				log.Warn("bad request", sl.Error(typeError))
+				msg := fmt.Sprintf("field %s must be type %s", typeError.Field, typeError.Type)
+				httplib.ResponseJSON(w, api.Error(msg), http.StatusBadRequest)
+				return
+			}
+			log.Error("failed to decode request body", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		if err := api.Validate(validator, p); err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.Error(err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		renewed, err := s.RenewStatus(context.Background(), id, p.Types)
+		if err != nil {
+			if errors.Is(err, storage.ErrStatusNotFound) {
+				log.Warn("could not renew status", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrStatusNotFound, http.StatusNotFound)
+				return
+			}
+			log.Error("failed to renew status", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("status renewed", slog.String("pods", strings.Join(renewed, ",")))
+
+		resp := api.OK(fmt.Sprintf("%d pods renewed", len(renewed)))
+		resp.Data = renewed
+		httplib.ResponseJSON(w, resp, http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
+
+// SetTTL задаёт время жизни подов статуса.
+// Пустое значение ttl восстанавливает время жизни по типам подов.
+func SetTTL(log *slog.Logger, s server.Storage) http.Handler {
+	log = log.With(sl.Component("api/status"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.status.SetTTL"
+
+		log := log.With(
+			sl.Operation(op),
+			sl.RequestID(request.GetID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			log.Warn("bad request", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrStatusNotFound, http.StatusNotFound)
+			return
+		}
+
+		p := api.StatusTTL{}
+		if err := httplib.DecodeJSON(r.Body, &p); err != nil {
+			var typeError *json.UnmarshalTypeError
+			if errors.As(err, &typeError) {
+				log.Warn("bad request", sl.Error(typeError))
+				msg := fmt.Sprintf("field %s must be type %s", typeError.Field, typeError.Type)
+				httplib.ResponseJSON(w, api.Error(msg), http.StatusBadRequest)
+				return
+			}
+			log.Error("failed to decode request body", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		var ttl *time.Duration
+		if p.TTL != nil && *p.TTL != "" {
+			d, err := time.ParseDuration(*p.TTL)
+			if err != nil || d < 0 {
+				log.Warn("bad request", slog.String("ttl", *p.TTL))
+				httplib.ResponseJSON(w, api.Error("field ttl must be a non-negative duration"), http.StatusBadRequest)
+				return
+			}
+			ttl = &d
+		}
+
+		if err := s.SetStatusTTL(context.Background(), id, ttl); err != nil {
+			if errors.Is(err, storage.ErrStatusNotFound) {
+				log.Warn("could not set status ttl", sl.Error(err))
+				httplib.ResponseJSON(w, api.ErrStatusNotFound, http.StatusNotFound)
+				return
+			}
+			log.Error("failed to set status ttl", sl.Error(err))
+			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
+			return
+		}
+
+		httplib.ResponseJSON(w, api.OK("status ttl updated successfully"), http.StatusOK)
+	}
+
+	return http.HandlerFunc(handler)
+}
@@ -16,7 +16,6 @@ import (
 	"github.com/korikhin/pod-sync/internal/server"
 	"github.com/korikhin/pod-sync/internal/server/middleware/request"
 	"github.com/korikhin/pod-sync/internal/storage"
-	"github.com/korikhin/pod-sync/internal/watcher"
 
 	"github.com/gorilla/mux"
 )
@@ -28,7 +27,7 @@ const needRestartValue = "true"
 
 // Update обновляет статус.
 // Регистрирует соответствующие операции с подами.
-func Update(log *slog.Logger, s server.Storage, wa *watcher.Watcher) http.Handler {
+func Update(log *slog.Logger, s server.Storage) http.Handler {
 	log = log.With(sl.Component("api/status"))
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -66,30 +65,50 @@ func Update(log *slog.Logger, s server.Storage, wa *watcher.Watcher) http.Handle
 			return
 		}
 
-		status := &models.Status{
-			ID: id,
-			X:  *p.X,
-			Y:  *p.Y,
-			Z:  *p.Z,
-		}
+		needRestart := r.URL.Query().Get(queryParamNeedRestart) == needRestartValue
+
+		// Идентификатор запроса сохраняется в зарегистрированных операциях
+		ctx := context.WithoutCancel(r.Context())
 
-		statusBefore, err := s.UpdateStatus(context.Background(), id, p)
+		ops, err := s.UpdateStatus(ctx, id, p, needRestart)
 		if err != nil {
 			if errors.Is(err, storage.ErrStatusNotFound) {
 				log.Warn("could not update status", sl.Error(err))
 				httplib.ResponseJSON(w, api.ErrStatusNotFound, http.StatusNotFound)
 				return
 			}
+			var capacityError *models.CapacityError
+			if errors.As(err, &capacityError) {
+				log.Warn("could not update status", sl.Error(err))
+				httplib.ResponseJSON(w, api.Error(capacityError.Error()), http.StatusConflict)
+				return
+			}
 			log.Error("failed to update status", sl.Error(err))
 			httplib.ResponseJSON(w, api.ErrInternal, http.StatusInternalServerError)
 			return
 		}
 
-		needRestart := r.URL.Query().Get(queryParamNeedRestart) == needRestartValue
-		ops := models.UpdateOperations(status, statusBefore, needRestart)
-		wa.QueueOperations(ops)
+		log.Info("status updated", slog.Int("operations", len(ops)))
+
+		for _, po := range ops {
+			if po.StatusID != id {
+				log.Warn("pod preempted", slog.String("pod_id", po.PodID), slog.Int("client_id", po.ClientID))
+			}
+		}
+
+		queued := make([]api.Operation, 0, len(ops))
+		for _, po := range ops {
+			queued = append(queued, api.Operation{
+				ID:            po.ID,
+				PodID:         po.PodID,
+				PreviousPodID: po.PrevPodID,
+				Operation:     po.Code.String(),
+			})
+		}
 
-		httplib.ResponseJSON(w, api.OK("status updated successfully"), http.StatusCreated)
+		resp := api.OK("status updated successfully")
+		resp.Data = queued
+		httplib.ResponseJSON(w, resp, http.StatusCreated)
 	}
 
 	return http.HandlerFunc(handler)
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"time"
 
 	"github.com/korikhin/pod-sync/internal/lib/api"
 	"github.com/korikhin/pod-sync/internal/models"
@@ -13,14 +14,77 @@ type Storage interface {
 	AddClient(ctx context.Context, p api.Client) (*models.Client, error)
 
 	// UpdateClient обновляет данные клиента.
-	// Возвращает возможную ошибку и статус, если требуется перезагрузка.
-	UpdateClient(ctx context.Context, id int, p api.Client) error
+	// Если изменились параметры подов (образ, версия, процессор или память),
+	// в той же транзакции регистрирует операции по перезапуску включённых подов.
+	// Возвращает зарегистрированные операции и возможную ошибку.
+	UpdateClient(ctx context.Context, id int, p api.Client) ([]models.PodOperation, error)
 
 	// DeleteClient удаляет клиента.
+	// Регистрирует операции по удалению активных подов.
 	// Возвращает соответствующий статус и возможную ошибку.
 	DeleteClient(ctx context.Context, id int) (*models.Status, error)
 
 	// UpdateStatus обновляет статус.
-	// Возвразает предыдущий статус и возможную ошибку.
-	UpdateStatus(ctx context.Context, id int, p api.Status) (*models.Status, error)
+	// Регистрирует соответствующие операции с подами.
+	// Возвращает зарегистрированные операции и возможную ошибку.
+	UpdateStatus(ctx context.Context, id int, p api.Status, needRestart bool) ([]models.PodOperation, error)
+
+	// RenewStatus продлевает время жизни включённых подов статуса.
+	// Если types заданы, продлеваются только поды указанных типов.
+	// Возвращает типы продлённых подов и возможную ошибку.
+	RenewStatus(ctx context.Context, id int, types []string) ([]string, error)
+
+	// SetStatusTTL задаёт время жизни подов статуса.
+	// Если ttl равен nil, действует время жизни по типам подов.
+	SetStatusTTL(ctx context.Context, id int, ttl *time.Duration) error
+
+	// RestartPods регистрирует операции перезапуска включённых подов клиентов,
+	// соответствующих фильтру.
+	// Возвращает зарегистрированные операции и возможную ошибку.
+	RestartPods(ctx context.Context, f models.RestartFilter) ([]models.PodOperation, error)
+
+	// AddSchedule добавляет расписание статуса.
+	AddSchedule(ctx context.Context, sc models.Schedule) (*models.Schedule, error)
+
+	// GetStatusSchedules возвращает расписания статуса.
+	GetStatusSchedules(ctx context.Context, statusID int) ([]models.Schedule, error)
+
+	// DeleteSchedule удаляет расписание.
+	DeleteSchedule(ctx context.Context, id int) error
+
+	// CreateRollout создаёт выкат и отбирает его клиентов.
+	CreateRollout(ctx context.Context, r models.Rollout) (*models.Rollout, error)
+
+	// GetRollouts возвращает выкаты в порядке создания.
+	GetRollouts(ctx context.Context) ([]models.Rollout, error)
+
+	// GetRollout возвращает выкат со статистикой текущей волны.
+	GetRollout(ctx context.Context, id int64) (*models.Rollout, error)
+
+	// CancelRollout отменяет выполняющийся выкат.
+	CancelRollout(ctx context.Context, id int64) error
+
+	// GetDeadLetters возвращает операции, которые не удалось выполнить
+	// за максимальное количество попыток.
+	GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error)
+
+	// RetryDeadLetters возвращает операции из списка dead letters в очередь.
+	// Если ids не заданы, возвращает в очередь все операции из списка.
+	// Возвращает количество операций, поставленных в очередь.
+	RetryDeadLetters(ctx context.Context, ids []int64) (int, error)
+
+	// GetOperationLog возвращает последние записи истории выполнения операций,
+	// начиная с самой поздней.
+	// Если clientID не равен нулю, возвращаются только операции над подами клиента.
+	// Если since задано, возвращаются только операции, начатые не ранее since.
+	GetOperationLog(ctx context.Context, clientID int, since time.Time) ([]models.OperationRecord, error)
+
+	// GetPreemptions возвращает последние записи о вытеснении подов,
+	// начиная с самой поздней.
+	// Если clientID не равен нулю, возвращаются только записи о подах клиента.
+	// Если since задано, возвращаются только записи не ранее since.
+	GetPreemptions(ctx context.Context, clientID int, since time.Time) ([]models.PreemptionRecord, error)
+
+	// GetOperation возвращает состояние операции.
+	GetOperation(ctx context.Context, id int64) (*models.OperationStatus, error)
 }
@@ -0,0 +1,285 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Ключ advisory-блокировки, сериализующей проверки ресурсов кластера
+const capacityLockKey int64 = leaderLockKey + 1
+
+// Максимальное количество записей о вытеснении, возвращаемых за один запрос
+const preemptionLogLimit = 1000
+
+// requester описывает клиента, включающего поды.
+type requester struct {
+	cpu      string
+	mem      string
+	priority float64
+}
+
+// admit проверяет, что включённым подам статуса хватит ресурсов кластера.
+// Если ресурсов недостаточно и политика вытеснения это допускает, выключает
+// поды клиентов с меньшим приоритетом и сохраняет записи о вытеснении.
+// Возвращает операции удаления вытесненных подов
+// или *models.CapacityError, если ресурсов недостаточно.
+//
+// Клиенты с некорректно заданными ресурсами (например, сохранёнными до
+// появления проверки) не учитываются и не вытесняются; о них пишется в лог.
+//
+// Проверки выполняются под блокировкой до завершения транзакции,
+// чтобы параллельные изменения статусов не превысили ресурсы в сумме.
+// Блокировка должна быть получена до блокировки и изменения строк статусов,
+// см. lockCapacity.
+func (s *Storage) admit(ctx context.Context, tx pgx.Tx, status *models.Status, r requester) ([]models.PodOperation, error) {
+	if s.capacity.IsZero() {
+		return nil, nil
+	}
+
+	requested, err := models.ParseResources(r.cpu, r.mem)
+	if err != nil {
+		return nil, fmt.Errorf("client %d: %w", status.ClientID, err)
+	}
+
+	if err := s.lockCapacity(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	query := `
+		select
+			s.id,
+			s."X",
+			s."Y",
+			s."Z",
+			s."X_gen",
+			s."Y_gen",
+			s."Z_gen",
+			c.id,
+			c.name,
+			c.cpu,
+			c.mem,
+			c.priority
+		from watcher.status s
+		join watcher.clients c on c.id = s.client_id
+		where s.id <> @id
+			and (s."X" or s."Y" or s."Z")
+		order by s.id
+	`
+	// Строки вытесняемых статусов блокируются до их изменения
+	if s.capacity.Preemption != models.PreemptNever {
+		query += ` for update of s`
+	}
+	args := pgx.NamedArgs{
+		"id": status.ID,
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		models.Candidate
+		cpu string
+		mem string
+	}
+
+	rowsCandidates, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (candidate, error) {
+		c := candidate{}
+		err := row.Scan(
+			&c.Status.ID,
+			&c.Status.X,
+			&c.Status.Y,
+			&c.Status.Z,
+			&c.Status.Gen[0],
+			&c.Status.Gen[1],
+			&c.Status.Gen[2],
+			&c.Status.ClientID,
+			&c.Status.ClientName,
+			&c.cpu,
+			&c.mem,
+			&c.Priority,
+		)
+		return c, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]models.Candidate, 0, len(rowsCandidates))
+	for _, c := range rowsCandidates {
+		res, err := models.ParseResources(c.cpu, c.mem)
+		if err != nil {
+			s.log.Warn("client resources are invalid, skipping capacity accounting",
+				slog.Int("client_id", c.Status.ClientID),
+				sl.Error(err),
+			)
+			continue
+		}
+		c.Resources = res
+		candidates = append(candidates, c.Candidate)
+	}
+
+	used := models.Resources{}
+	for _, c := range candidates {
+		used = used.Add(c.Resources.Times(c.Status.EnabledPods()))
+	}
+
+	preempted, err := s.capacity.Preempt(used, requested.Times(status.EnabledPods()), r.priority, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	priorities := make(map[int]float64, len(candidates))
+	for _, c := range candidates {
+		priorities[c.Status.ID] = c.Priority
+	}
+
+	queryUpdate := `
+		update watcher.status
+		set (
+			"X",
+			"Y",
+			"Z"
+		) = (
+			@X,
+			@Y,
+			@Z
+		)
+		where id = @id;
+	`
+	queryRecord := `
+		insert into watcher.preemptions (
+			status_id,
+			client_id,
+			pods,
+			priority,
+			preempted_by_status_id,
+			preempted_by_client_id,
+			preempted_by_priority,
+			request_id
+		) values (
+			@status_id,
+			@client_id,
+			@pods,
+			@priority,
+			@by_status_id,
+			@by_client_id,
+			@by_priority,
+			nullif(@request_id, '')
+		);
+	`
+
+	ops := make([]models.PodOperation, 0)
+	for _, p := range preempted {
+		argsUpdate := pgx.NamedArgs{
+			"id": p.After.ID,
+			"X":  p.After.X,
+			"Y":  p.After.Y,
+			"Z":  p.After.Z,
+		}
+		if _, err := tx.Exec(ctx, queryUpdate, argsUpdate); err != nil {
+			return nil, err
+		}
+
+		argsRecord := pgx.NamedArgs{
+			"status_id":    p.After.ID,
+			"client_id":    p.After.ClientID,
+			"pods":         p.Types,
+			"priority":     priorities[p.After.ID],
+			"by_status_id": status.ID,
+			"by_client_id": status.ClientID,
+			"by_priority":  r.priority,
+			"request_id":   request.GetID(ctx),
+		}
+		if _, err := tx.Exec(ctx, queryRecord, argsRecord); err != nil {
+			return nil, err
+		}
+
+		statusOps, err := models.UpdateOperations(s.namer, &p.After, &p.Before, false)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, statusOps...)
+	}
+
+	return ops, nil
+}
+
+// lockCapacity получает блокировку проверок ресурсов кластера
+// до завершения транзакции. Повторное получение в той же транзакции допускается.
+// Если ресурсы кластера не ограничены, блокировка не требуется.
+func (s *Storage) lockCapacity(ctx context.Context, tx pgx.Tx) error {
+	if s.capacity.IsZero() {
+		return nil
+	}
+	_, err := tx.Exec(ctx, "select pg_advisory_xact_lock($1);", capacityLockKey)
+	return err
+}
+
+// GetPreemptions возвращает последние записи о вытеснении подов,
+// начиная с самой поздней.
+// Если clientID не равен нулю, возвращаются только записи о подах клиента.
+// Если since задано, возвращаются только записи не ранее since.
+func (s *Storage) GetPreemptions(ctx context.Context, clientID int, since time.Time) ([]models.PreemptionRecord, error) {
+	const op = "storage.postgres.GetPreemptions"
+
+	query := `
+		select
+			id,
+			status_id,
+			client_id,
+			pods,
+			priority,
+			preempted_by_status_id,
+			preempted_by_client_id,
+			preempted_by_priority,
+			coalesce(request_id, ''),
+			created_at
+		from watcher.preemptions
+		where (@client_id = 0 or client_id = @client_id)
+			and created_at >= @since
+		order by id desc
+		limit @limit;
+	`
+	args := pgx.NamedArgs{
+		"client_id": clientID,
+		"since":     since.UTC(),
+		"limit":     preemptionLogLimit,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.PreemptionRecord, error) {
+		r := models.PreemptionRecord{}
+		err := row.Scan(
+			&r.ID,
+			&r.StatusID,
+			&r.ClientID,
+			&r.Types,
+			&r.Priority,
+			&r.ByStatusID,
+			&r.ByClientID,
+			&r.ByPriority,
+			&r.RequestID,
+			&r.CreatedAt,
+		)
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
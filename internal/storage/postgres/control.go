@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IsPaused сообщает, приостановлено ли выполнение операций.
+func (s *Storage) IsPaused(ctx context.Context) (bool, error) {
+	const op = "storage.postgres.IsPaused"
+
+	query := `
+		select paused
+		from watcher.control;
+	`
+
+	var paused bool
+	if err := s.pool.QueryRow(ctx, query).Scan(&paused); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return paused, nil
+}
+
+// SetPaused приостанавливает или возобновляет выполнение операций.
+func (s *Storage) SetPaused(ctx context.Context, paused bool) error {
+	const op = "storage.postgres.SetPaused"
+
+	query := `
+		update watcher.control
+		set (
+			paused,
+			updated_at
+		) = (
+			@paused,
+			timezone('UTC', now())
+		);
+	`
+	args := pgx.NamedArgs{
+		"paused": paused,
+	}
+
+	if _, err := s.pool.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
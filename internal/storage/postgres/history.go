@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Максимальное количество записей истории, возвращаемых за один запрос
+const operationLogLimit = 1000
+
+// LogOperation сохраняет запись о выполнении операции с подом.
+func (s *Storage) LogOperation(ctx context.Context, r models.OperationRecord) error {
+	const op = "storage.postgres.LogOperation"
+
+	query := `
+		insert into watcher.operation_log (
+			operation_id,
+			pod_id,
+			previous_pod_id,
+			code,
+			status_id,
+			client_id,
+			request_id,
+			attempt,
+			result,
+			error,
+			started_at,
+			finished_at
+		) values (
+			nullif(@operation_id::bigint, 0),
+			@pod_id,
+			nullif(@previous_pod_id, ''),
+			@code,
+			nullif(@status_id, 0),
+			nullif(@client_id, 0),
+			nullif(@request_id, ''),
+			@attempt,
+			@result,
+			nullif(@error, ''),
+			@started_at,
+			@finished_at
+		);
+	`
+	args := pgx.NamedArgs{
+		"operation_id":    r.Op.ID,
+		"pod_id":          r.Op.PodID,
+		"previous_pod_id": r.Op.PrevPodID,
+		"code":            r.Op.Code,
+		"status_id":       r.Op.StatusID,
+		"client_id":       r.Op.ClientID,
+		"request_id":      r.Op.RequestID,
+		"attempt":         r.Attempt,
+		"result":          r.Result,
+		"error":           r.Error,
+		"started_at":      r.StartedAt.UTC(),
+		"finished_at":     r.FinishedAt.UTC(),
+	}
+
+	if _, err := s.pool.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetOperationLog возвращает последние записи истории выполнения операций,
+// начиная с самой поздней.
+// Если clientID не равен нулю, возвращаются только операции над подами клиента.
+// Если since задано, возвращаются только операции, начатые не ранее since.
+func (s *Storage) GetOperationLog(ctx context.Context, clientID int, since time.Time) ([]models.OperationRecord, error) {
+	const op = "storage.postgres.GetOperationLog"
+
+	query := `
+		select
+			id,
+			coalesce(operation_id, 0),
+			pod_id,
+			coalesce(previous_pod_id, ''),
+			code,
+			coalesce(status_id, 0),
+			coalesce(client_id, 0),
+			coalesce(request_id, ''),
+			attempt,
+			result,
+			coalesce(error, ''),
+			started_at,
+			finished_at
+		from watcher.operation_log
+		where (@client_id = 0 or client_id = @client_id)
+			and started_at >= @since
+		order by id desc
+		limit @limit;
+	`
+	args := pgx.NamedArgs{
+		"client_id": clientID,
+		"since":     since.UTC(),
+		"limit":     operationLogLimit,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OperationRecord, error) {
+		r := models.OperationRecord{}
+		err := row.Scan(
+			&r.ID,
+			&r.Op.ID,
+			&r.Op.PodID,
+			&r.Op.PrevPodID,
+			&r.Op.Code,
+			&r.Op.StatusID,
+			&r.Op.ClientID,
+			&r.Op.RequestID,
+			&r.Attempt,
+			&r.Result,
+			&r.Error,
+			&r.StartedAt,
+			&r.FinishedAt,
+		)
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/storage"
+	"github.com/korikhin/pod-sync/internal/watcher"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Ключ advisory-блокировки, определяющей лидера
+const leaderLockKey int64 = 0x706f642d73796e63 // pod-sync
+
+// leadership удерживает advisory-блокировку на выделенном соединении.
+// Блокировка освобождается сервером при разрыве соединения.
+type leadership struct {
+	mu     sync.Mutex
+	conn   *pgxpool.Conn
+	closed bool // Соединение возвращено в пул
+}
+
+// TryLead пытается захватить блокировку лидера.
+// Возвращает nil, если блокировка удерживается другим экземпляром сервиса.
+func (s *Storage) TryLead(ctx context.Context) (watcher.Leadership, error) {
+	const op = "storage.postgres.TryLead"
+
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, sanitizeError(err))
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "select pg_try_advisory_lock($1);", leaderLockKey).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("%s: %w", op, sanitizeError(err))
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil
+	}
+
+	return &leadership{conn: conn}, nil
+}
+
+// Check проверяет, что соединение, удерживающее блокировку, активно.
+func (l *leadership) Check(ctx context.Context) error {
+	const op = "storage.postgres.leadership.Check"
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return fmt.Errorf("%s: %w", op, storage.ErrConnectionInvalid)
+	}
+	if err := l.conn.Ping(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, sanitizeError(err))
+	}
+	return nil
+}
+
+// Resign освобождает блокировку и возвращает соединение в пул.
+// Если освободить блокировку не удалось, соединение закрывается.
+func (l *leadership) Resign(ctx context.Context) error {
+	const op = "storage.postgres.leadership.Resign"
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	defer l.conn.Release()
+
+	if _, err := l.conn.Exec(ctx, "select pg_advisory_unlock($1);", leaderLockKey); err != nil {
+		l.conn.Conn().Close(ctx)
+		return fmt.Errorf("%s: %w", op, sanitizeError(err))
+	}
+	return nil
+}
+
+// Abort закрывает соединение, удерживающее блокировку, и возвращает его в пул.
+// Блокировка освобождается сервером при разрыве соединения.
+func (l *leadership) Abort() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+	l.closed = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	l.conn.Conn().Close(ctx)
+	l.conn.Release()
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Канал уведомлений об изменении статусов вне API сервиса
+const channelStatus = "watcher_status"
+
+// ListenStatus подписывается на уведомления об изменении статусов
+// и вызывает fn с идентификатором изменённого статуса.
+// Блокируется до отмены ctx или разрыва соединения.
+func (s *Storage) ListenStatus(ctx context.Context, fn func(id int)) error {
+	const op = "storage.postgres.ListenStatus"
+
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, sanitizeError(err))
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "listen "+channelStatus+";"); err != nil {
+		return fmt.Errorf("%s: %w", op, sanitizeError(err))
+	}
+	defer func() {
+		// Соединение с подпиской не возвращается в пул
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn.Conn().Close(ctx)
+	}()
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", op, sanitizeError(err))
+		}
+
+		id, err := strconv.Atoi(n.Payload)
+		if err != nil {
+			continue
+		}
+		fn(id)
+	}
+}
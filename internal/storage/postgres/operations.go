@@ -0,0 +1,624 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Состояния операций
+const (
+	opStatePending = "pending"
+	opStateRunning = "running"
+	opStateDead    = "dead"
+)
+
+// insertOperations регистрирует операции с подами в рамках транзакции.
+// Заполняет идентификаторы операций и идентификатор запроса из ctx.
+func insertOperations(ctx context.Context, tx pgx.Tx, ops []models.PodOperation) error {
+	query := `
+		insert into watcher.operations (
+			pod_id,
+			previous_pod_id,
+			code,
+			status_id,
+			client_id,
+			request_id
+		) values (
+			@pod_id,
+			nullif(@previous_pod_id, ''),
+			@code,
+			@status_id,
+			@client_id,
+			nullif(@request_id, '')
+		)
+		returning id;
+	`
+
+	requestID := request.GetID(ctx)
+	for i := range ops {
+		ops[i].RequestID = requestID
+		args := pgx.NamedArgs{
+			"pod_id":          ops[i].PodID,
+			"previous_pod_id": ops[i].PrevPodID,
+			"code":            ops[i].Code,
+			"status_id":       ops[i].StatusID,
+			"client_id":       ops[i].ClientID,
+			"request_id":      ops[i].RequestID,
+		}
+		if err := tx.QueryRow(ctx, query, args).Scan(&ops[i].ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ClaimOperations переводит в состояние выполнения не более limit операций,
+// готовых к выполнению, и возвращает их в порядке убывания приоритета клиента,
+// а при равном приоритете — в порядке регистрации.
+//
+// Операция над подом не выбирается, пока предшествующая ей операция
+// над тем же подом выполняется или отложена. Для перезапуска surge
+// тем же подом считается и под с прежним именем.
+// Операции, заблокированные другими транзакциями, пропускаются.
+// Если statusID не равен нулю, выбираются только операции над подами статуса.
+func (s *Storage) ClaimOperations(ctx context.Context, limit int, statusID int) ([]models.PodOperation, error) {
+	const op = "storage.postgres.ClaimOperations"
+
+	query := `
+		with claimed as (
+			update watcher.operations
+			set (
+				state,
+				claimed_at,
+				updated_at
+			) = (
+				@running,
+				timezone('UTC', now()),
+				timezone('UTC', now())
+			)
+			where id in (
+				select o.id
+				from watcher.operations o
+				left join watcher.status s on s.id = o.status_id
+				left join watcher.clients c on c.id = s.client_id
+				where o.state = @pending
+					and o.not_before <= timezone('UTC', now())
+					and (@status_id = 0 or o.status_id = @status_id)
+					and not exists (
+						select 1
+						from watcher.operations p
+						where (
+								p.pod_id in (o.pod_id, o.previous_pod_id)
+								or p.previous_pod_id = o.pod_id
+							)
+							and p.id < o.id
+							and (
+								p.state = @running
+								or (p.state = @pending and p.not_before > timezone('UTC', now()))
+							)
+					)
+				order by coalesce(c.priority, 0) desc, o.id
+				limit @limit
+				for update of o skip locked
+			)
+			returning
+				id,
+				pod_id,
+				previous_pod_id,
+				code,
+				status_id,
+				client_id,
+				request_id,
+				attempts
+		)
+		select
+			o.id,
+			o.pod_id,
+			coalesce(o.previous_pod_id, ''),
+			o.code,
+			coalesce(o.status_id, 0),
+			coalesce(o.client_id, 0),
+			coalesce(o.request_id, ''),
+			o.attempts,
+			coalesce(c.priority, 0)
+		from claimed o
+		left join watcher.status s on s.id = o.status_id
+		left join watcher.clients c on c.id = s.client_id;
+	`
+	args := pgx.NamedArgs{
+		"pending":   opStatePending,
+		"running":   opStateRunning,
+		"limit":     limit,
+		"status_id": statusID,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ops, err := pgx.CollectRows(rows, scanOperation)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	models.SortOperations(ops)
+	return ops, nil
+}
+
+// CompleteOperation удаляет успешно выполненную операцию.
+func (s *Storage) CompleteOperation(ctx context.Context, id int64) error {
+	const op = "storage.postgres.CompleteOperation"
+
+	query := `
+		delete from watcher.operations
+		where id = @id;
+	`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	if _, err := s.pool.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// CancelOperations удаляет операции, исключённые из очереди без выполнения,
+// и сохраняет их в истории операций как отменённые.
+func (s *Storage) CancelOperations(ctx context.Context, ids []int64) error {
+	const op = "storage.postgres.CancelOperations"
+
+	query := `
+		with cancelled as (
+			delete from watcher.operations
+			where id = any(@ids)
+			returning
+				id,
+				pod_id,
+				previous_pod_id,
+				code,
+				status_id,
+				client_id,
+				request_id,
+				attempts
+		)
+		insert into watcher.operation_log (
+			operation_id,
+			pod_id,
+			previous_pod_id,
+			code,
+			status_id,
+			client_id,
+			request_id,
+			attempt,
+			result,
+			started_at,
+			finished_at
+		)
+		select
+			id,
+			pod_id,
+			previous_pod_id,
+			code,
+			status_id,
+			client_id,
+			request_id,
+			attempts,
+			@cancelled,
+			timezone('UTC', now()),
+			timezone('UTC', now())
+		from cancelled;
+	`
+	args := pgx.NamedArgs{
+		"ids":       ids,
+		"cancelled": models.OpStateCancelled,
+	}
+
+	if _, err := s.pool.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RetryOperation возвращает неудачную операцию в очередь.
+// Операция будет выбрана для выполнения не ранее, чем через delay.
+func (s *Storage) RetryOperation(ctx context.Context, po models.PodOperation, delay time.Duration, cause error) error {
+	const op = "storage.postgres.RetryOperation"
+
+	query := `
+		update watcher.operations
+		set (
+			state,
+			attempts,
+			not_before,
+			error,
+			updated_at
+		) = (
+			@pending,
+			@attempts,
+			timezone('UTC', now()) + @delay::interval,
+			@error,
+			timezone('UTC', now())
+		)
+		where id = @id;
+	`
+	args := pgx.NamedArgs{
+		"id":       po.ID,
+		"pending":  opStatePending,
+		"attempts": po.Attempts,
+		"delay":    delay,
+		"error":    cause.Error(),
+	}
+
+	if _, err := s.pool.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// BuryOperation переносит операцию, исчерпавшую попытки, в список dead letters.
+func (s *Storage) BuryOperation(ctx context.Context, po models.PodOperation, cause error) error {
+	const op = "storage.postgres.BuryOperation"
+
+	query := `
+		update watcher.operations
+		set (
+			state,
+			attempts,
+			error,
+			updated_at
+		) = (
+			@dead,
+			@attempts,
+			@error,
+			timezone('UTC', now())
+		)
+		where id = @id;
+	`
+	args := pgx.NamedArgs{
+		"id":       po.ID,
+		"dead":     opStateDead,
+		"attempts": po.Attempts,
+		"error":    cause.Error(),
+	}
+
+	if _, err := s.pool.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReleaseOperations возвращает выбранные, но не выполненные операции в очередь.
+func (s *Storage) ReleaseOperations(ctx context.Context, ids []int64) error {
+	const op = "storage.postgres.ReleaseOperations"
+
+	query := `
+		update watcher.operations
+		set (
+			state,
+			updated_at
+		) = (
+			@pending,
+			timezone('UTC', now())
+		)
+		where state = @running
+			and id = any(@ids::bigint[]);
+	`
+	args := pgx.NamedArgs{
+		"pending": opStatePending,
+		"running": opStateRunning,
+		"ids":     ids,
+	}
+
+	if _, err := s.pool.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReleaseStaleOperations возвращает в очередь операции, выбранные для выполнения
+// ранее, чем timeout назад. Возвращает количество возвращённых операций.
+func (s *Storage) ReleaseStaleOperations(ctx context.Context, timeout time.Duration) (int64, error) {
+	const op = "storage.postgres.ReleaseStaleOperations"
+
+	query := `
+		update watcher.operations
+		set (
+			state,
+			updated_at
+		) = (
+			@pending,
+			timezone('UTC', now())
+		)
+		where state = @running
+			and coalesce(claimed_at, updated_at) < timezone('UTC', now()) - @timeout::interval;
+	`
+	args := pgx.NamedArgs{
+		"pending": opStatePending,
+		"running": opStateRunning,
+		"timeout": timeout,
+	}
+
+	tag, err := s.pool.Exec(ctx, query, args)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// CountQueuedOperations возвращает количество невыполненных операций.
+func (s *Storage) CountQueuedOperations(ctx context.Context) (int, error) {
+	const op = "storage.postgres.CountQueuedOperations"
+
+	query := `
+		select count(*)
+		from watcher.operations
+		where state in (@pending, @running);
+	`
+	args := pgx.NamedArgs{
+		"pending": opStatePending,
+		"running": opStateRunning,
+	}
+
+	var n int
+	if err := s.pool.QueryRow(ctx, query, args).Scan(&n); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// GetQueuedPods возвращает имена подов, для которых есть невыполненные операции,
+// включая прежние имена подов, перезапускаемых surge.
+func (s *Storage) GetQueuedPods(ctx context.Context) ([]string, error) {
+	const op = "storage.postgres.GetQueuedPods"
+
+	query := `
+		select pod_id
+		from watcher.operations
+		where state in (@pending, @running)
+		union
+		select previous_pod_id
+		from watcher.operations
+		where state in (@pending, @running)
+			and previous_pod_id is not null;
+	`
+	args := pgx.NamedArgs{
+		"pending": opStatePending,
+		"running": opStateRunning,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pods, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pods, nil
+}
+
+// GetDeadLetters возвращает операции, которые не удалось выполнить
+// за максимальное количество попыток.
+func (s *Storage) GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	const op = "storage.postgres.GetDeadLetters"
+
+	query := `
+		select
+			id,
+			pod_id,
+			code,
+			attempts,
+			coalesce(error, ''),
+			updated_at
+		from watcher.operations
+		where state = @dead
+		order by id;
+	`
+	args := pgx.NamedArgs{
+		"dead": opStateDead,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.DeadLetter, error) {
+		d := models.DeadLetter{}
+		err := row.Scan(
+			&d.Op.ID,
+			&d.Op.PodID,
+			&d.Op.Code,
+			&d.Op.Attempts,
+			&d.Error,
+			&d.FailedAt,
+		)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
+
+// RetryDeadLetters возвращает операции из списка dead letters в очередь.
+// Если ids не заданы, возвращает в очередь все операции из списка.
+// Счётчик попыток операций сбрасывается.
+// Возвращает количество операций, поставленных в очередь.
+func (s *Storage) RetryDeadLetters(ctx context.Context, ids []int64) (int, error) {
+	const op = "storage.postgres.RetryDeadLetters"
+
+	query := `
+		update watcher.operations
+		set (
+			state,
+			attempts,
+			not_before,
+			error,
+			updated_at
+		) = (
+			@pending,
+			0,
+			timezone('UTC', now()),
+			null,
+			timezone('UTC', now())
+		)
+		where state = @dead
+			and (@ids::bigint[] is null or id = any(@ids));
+	`
+	args := pgx.NamedArgs{
+		"pending": opStatePending,
+		"dead":    opStateDead,
+		"ids":     ids,
+	}
+
+	tag, err := s.pool.Exec(ctx, query, args)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if ids != nil && tag.RowsAffected() == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrOperationNotFound)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// GetOperation возвращает состояние операции.
+// Состояние выполненной или отменённой операции определяется по истории операций.
+func (s *Storage) GetOperation(ctx context.Context, id int64) (*models.OperationStatus, error) {
+	const op = "storage.postgres.GetOperation"
+
+	query := `
+		select
+			id,
+			pod_id,
+			coalesce(previous_pod_id, ''),
+			code,
+			coalesce(status_id, 0),
+			coalesce(client_id, 0),
+			coalesce(request_id, ''),
+			attempts,
+			state,
+			coalesce(error, ''),
+			updated_at
+		from watcher.operations
+		where id = @id;
+	`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	st := &models.OperationStatus{}
+	var state string
+	err := s.pool.QueryRow(ctx, query, args).Scan(
+		&st.Op.ID,
+		&st.Op.PodID,
+		&st.Op.PrevPodID,
+		&st.Op.Code,
+		&st.Op.StatusID,
+		&st.Op.ClientID,
+		&st.Op.RequestID,
+		&st.Op.Attempts,
+		&state,
+		&st.Error,
+		&st.UpdatedAt,
+	)
+	if err == nil {
+		switch state {
+		case opStateRunning:
+			st.State = models.OpStateRunning
+		case opStateDead:
+			st.State = models.OpStateFailed
+		default:
+			st.State = models.OpStatePending
+		}
+		return st, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	queryLog := `
+		select
+			operation_id,
+			pod_id,
+			coalesce(previous_pod_id, ''),
+			code,
+			coalesce(status_id, 0),
+			coalesce(client_id, 0),
+			coalesce(request_id, ''),
+			attempt,
+			result,
+			coalesce(error, ''),
+			finished_at
+		from watcher.operation_log
+		where operation_id = @id
+		order by id desc
+		limit 1;
+	`
+
+	var result string
+	if err := s.pool.QueryRow(ctx, queryLog, args).Scan(
+		&st.Op.ID,
+		&st.Op.PodID,
+		&st.Op.PrevPodID,
+		&st.Op.Code,
+		&st.Op.StatusID,
+		&st.Op.ClientID,
+		&st.Op.RequestID,
+		&st.Op.Attempts,
+		&result,
+		&st.Error,
+		&st.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrOperationNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch result {
+	case models.OpStateSucceeded, models.OpStateCancelled:
+		st.State = result
+	default:
+		st.State = models.OpStateFailed
+	}
+	return st, nil
+}
+
+func scanOperation(row pgx.CollectableRow) (models.PodOperation, error) {
+	po := models.PodOperation{}
+	err := row.Scan(
+		&po.ID,
+		&po.PodID,
+		&po.PrevPodID,
+		&po.Code,
+		&po.StatusID,
+		&po.ClientID,
+		&po.RequestID,
+		&po.Attempts,
+		&po.Priority,
+	)
+	return po, err
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 
 	"github.com/korikhin/pod-sync/internal/config"
 	"github.com/korikhin/pod-sync/internal/lib/api"
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
 	"github.com/korikhin/pod-sync/internal/models"
 	"github.com/korikhin/pod-sync/internal/server"
 	"github.com/korikhin/pod-sync/internal/storage"
+	"github.com/korikhin/pod-sync/internal/watcher"
 
 	codes "github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
@@ -19,11 +22,15 @@ import (
 )
 
 type Storage struct {
-	pool *pgxpool.Pool
+	log      *slog.Logger
+	pool     *pgxpool.Pool
+	namer    *models.PodNamer
+	capacity models.Capacity
 }
 
 // New создает и возвращает пул соединений к базе данных PostgreSQL.
-// Функция принимает контекст и конфигурацию хранилища.
+// Функция принимает контекст, логгер, конфигурацию хранилища, правило
+// именования подов и ресурсы кластера, доступные подам.
 // Возвращает объект Storage с инициализированным пулом соединений и возможную ошибку.
 //
 // Процесс работы функции:
@@ -32,7 +39,7 @@ type Storage struct {
 //   - Создает пул соединений с заданной конфигурацией.
 //   - Выполняет пинг базы данных для проверки соединения.
 //   - Возвращает объект Storage с инициализированным пулом.
-func New(ctx context.Context, cfg config.Storage) (*Storage, error) {
+func New(ctx context.Context, log *slog.Logger, cfg config.Storage, n *models.PodNamer, c models.Capacity) (*Storage, error) {
 	const op = "storage.postgres.New"
 
 	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
@@ -54,7 +61,9 @@ func New(ctx context.Context, cfg config.Storage) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", op, sanitizeError(err))
 	}
 
-	return &Storage{pool: pool}, nil
+	log = log.With(sl.Component("storage/postgres"))
+
+	return &Storage{log: log, pool: pool, namer: n, capacity: c}, nil
 }
 
 // Stop закрывает все соединения в пуле и отклоняет новые запросы.
@@ -92,6 +101,23 @@ func sanitizeError(err error) error {
 	return err
 }
 
+// begin начинает транзакцию от имени API сервиса.
+// Изменения статусов в такой транзакции не порождают уведомлений,
+// так как соответствующие операции регистрируются самим сервисом.
+func (s *Storage) begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "select set_config('watcher.origin', 'api', true);"); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return tx, nil
+}
+
 var _ server.Storage = (*Storage)(nil)
 
 // AddClient создаёт нового клиента и первоначальный статус.
@@ -99,7 +125,7 @@ var _ server.Storage = (*Storage)(nil)
 func (s *Storage) AddClient(ctx context.Context, p api.Client) (*models.Client, error) {
 	const op = "storage.postgres.AddClient"
 
-	tx, err := s.pool.Begin(ctx)
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -112,14 +138,16 @@ func (s *Storage) AddClient(ctx context.Context, p api.Client) (*models.Client,
 			image,
 			cpu,
 			mem,
-			priority
+			priority,
+			restart_strategy
 		) values (
 			@name,
 			@version,
 			@image,
 			@cpu,
 			@mem,
-			@priority
+			@priority,
+			coalesce(@restart_strategy::varchar, @recreate)
 		)
 		returning
 			id,
@@ -129,6 +157,7 @@ func (s *Storage) AddClient(ctx context.Context, p api.Client) (*models.Client,
 			cpu,
 			mem,
 			priority,
+			restart_strategy,
 			created_at,
 			updated_at;
 	`
@@ -139,6 +168,9 @@ func (s *Storage) AddClient(ctx context.Context, p api.Client) (*models.Client,
 		"cpu":      p.CPU,
 		"mem":      p.Memory,
 		"priority": p.Priority,
+
+		"restart_strategy": p.RestartStrategy,
+		"recreate":         models.RestartRecreate,
 	}
 
 	client := &models.Client{}
@@ -150,6 +182,7 @@ func (s *Storage) AddClient(ctx context.Context, p api.Client) (*models.Client,
 		&client.CPU,
 		&client.Memory,
 		&client.Priority,
+		&client.Restart,
 		&client.CreatedAt,
 		&client.UpdatedAt,
 	); err != nil {
@@ -161,13 +194,20 @@ func (s *Storage) AddClient(ctx context.Context, p api.Client) (*models.Client,
 			client_id
 		) values (
 			@client_id
-		);
+		)
+		returning id;
 	`
 	argsStatus := pgx.NamedArgs{
 		"client_id": client.ID,
 	}
 
-	if _, err := tx.Exec(ctx, queryStatus, argsStatus); err != nil {
+	status := &models.Status{ClientID: client.ID, ClientName: client.Name}
+	if err := tx.QueryRow(ctx, queryStatus, argsStatus).Scan(&status.ID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Имя клиента должно позволять сформировать имена подов
+	if err := s.namer.Check(status); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -179,10 +219,43 @@ func (s *Storage) AddClient(ctx context.Context, p api.Client) (*models.Client,
 }
 
 // UpdateClient обновляет данные клиента.
-// Возвращает возможную ошибку и статус, если требуется перезагрузка.
-func (s *Storage) UpdateClient(ctx context.Context, id int, p api.Client) error {
+// Если изменились параметры подов (образ, версия, процессор или память),
+// в той же транзакции регистрирует операции по перезапуску включённых подов.
+// Возвращает зарегистрированные операции и возможную ошибку.
+func (s *Storage) UpdateClient(ctx context.Context, id int, p api.Client) ([]models.PodOperation, error) {
 	const op = "storage.postgres.UpdateClient"
 
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	queryBefore := `
+		select
+			version,
+			image,
+			cpu,
+			mem
+		from watcher.clients
+		where id = @id
+		for update;
+	`
+	argsBefore := pgx.NamedArgs{
+		"id": id,
+	}
+
+	var version int
+	var image, cpu, mem string
+	if err := tx.QueryRow(ctx, queryBefore, argsBefore).Scan(&version, &image, &cpu, &mem); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrClientNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	needRestart := version != *p.Version || image != *p.Image || cpu != *p.CPU || mem != *p.Memory
+
 	query := `
 		update watcher.clients
 		set (
@@ -192,6 +265,7 @@ func (s *Storage) UpdateClient(ctx context.Context, id int, p api.Client) error
 			cpu,
 			mem,
 			priority,
+			restart_strategy,
 			updated_at
 		) = (
 			@name,
@@ -200,6 +274,7 @@ func (s *Storage) UpdateClient(ctx context.Context, id int, p api.Client) error
 			@cpu,
 			@mem,
 			@priority,
+			coalesce(@restart_strategy::varchar, restart_strategy),
 			timezone('UTC', now())
 		)
 		where id = @id;
@@ -212,25 +287,72 @@ func (s *Storage) UpdateClient(ctx context.Context, id int, p api.Client) error
 		"cpu":      p.CPU,
 		"mem":      p.Memory,
 		"priority": p.Priority,
+
+		"restart_strategy": p.RestartStrategy,
 	}
 
-	tag, err := s.pool.Exec(ctx, query, args)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if _, err := tx.Exec(ctx, query, args); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("%s: %w", op, storage.ErrClientNotFound)
+
+	queryStatus := `
+		select
+			id,
+			"X",
+			"Y",
+			"Z",
+			"X_gen",
+			"Y_gen",
+			"Z_gen"
+		from watcher.status
+		where client_id = @client_id;
+	`
+	argsStatus := pgx.NamedArgs{
+		"client_id": id,
+	}
+
+	status := &models.Status{ClientID: id, ClientName: *p.Name}
+	if err := tx.QueryRow(ctx, queryStatus, argsStatus).Scan(
+		&status.ID,
+		&status.X,
+		&status.Y,
+		&status.Z,
+		&status.Gen[0],
+		&status.Gen[1],
+		&status.Gen[2],
+	); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		status = nil
+	} else if err := s.namer.Check(status); err != nil {
+		// Имя клиента должно позволять сформировать имена подов
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Запущенные поды пересоздаются с новыми параметрами
+	var ops []models.PodOperation
+	if needRestart && status != nil && status.EnabledPods() > 0 {
+		ops, err = restartPods(ctx, tx, s.namer, models.RestartFilter{ClientIDs: []int{id}})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
 	}
 
-	return nil
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ops, nil
 }
 
 // DeleteClient удаляет клиента.
+// Регистрирует операции по удалению активных подов.
 // Возвращает соответствующий статус и возможную ошибку.
 func (s *Storage) DeleteClient(ctx context.Context, id int) (*models.Status, error) {
 	const op = "storage.postgres.DeleteClient"
 
-	tx, err := s.pool.Begin(ctx)
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -238,12 +360,19 @@ func (s *Storage) DeleteClient(ctx context.Context, id int) (*models.Status, err
 
 	queryStatus := `
 		select
-			id,
-			"X",
-			"Y",
-			"Z"
-		from watcher.status
-		where client_id = @client_id;
+			s.id,
+			s."X",
+			s."Y",
+			s."Z",
+			s."X_gen",
+			s."Y_gen",
+			s."Z_gen",
+			c.id,
+			c.name
+		from watcher.status s
+		join watcher.clients c on c.id = s.client_id
+		where s.client_id = @client_id
+		for update of s;
 	`
 	argsStatus := pgx.NamedArgs{
 		"client_id": id,
@@ -255,6 +384,11 @@ func (s *Storage) DeleteClient(ctx context.Context, id int) (*models.Status, err
 		&status.X,
 		&status.Y,
 		&status.Z,
+		&status.Gen[0],
+		&status.Gen[1],
+		&status.Gen[2],
+		&status.ClientID,
+		&status.ClientName,
 	)
 
 	query := `
@@ -279,6 +413,14 @@ func (s *Storage) DeleteClient(ctx context.Context, id int) (*models.Status, err
 		}
 	}
 
+	ops, err := models.DeleteOperations(s.namer, status)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := insertOperations(ctx, tx, ops); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -287,33 +429,60 @@ func (s *Storage) DeleteClient(ctx context.Context, id int) (*models.Status, err
 }
 
 // UpdateStatus обновляет статус.
-// Возвразает предыдущий статус и возможную ошибку.
-func (s *Storage) UpdateStatus(ctx context.Context, id int, p api.Status) (*models.Status, error) {
+// Регистрирует соответствующие операции с подами.
+// Возвращает зарегистрированные операции и возможную ошибку.
+func (s *Storage) UpdateStatus(ctx context.Context, id int, p api.Status, needRestart bool) ([]models.PodOperation, error) {
 	const op = "storage.postgres.UpdateStatus"
 
-	tx, err := s.pool.Begin(ctx)
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer tx.Rollback(ctx)
 
+	// Блокировка ресурсов кластера берётся до блокировки строки статуса
+	if err := s.lockCapacity(ctx, tx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	queryGet := `
 		select
-			"X",
-			"Y",
-			"Z"
-		from watcher.status
-		where id = @id;
+			s."X",
+			s."Y",
+			s."Z",
+			s."X_gen",
+			s."Y_gen",
+			s."Z_gen",
+			c.id,
+			c.name,
+			c.restart_strategy,
+			c.cpu,
+			c.mem,
+			c.priority
+		from watcher.status s
+		join watcher.clients c on c.id = s.client_id
+		where s.id = @id
+		for update of s;
 	`
 	argsGet := pgx.NamedArgs{
 		"id": id,
 	}
 
 	statusBefore := &models.Status{ID: id}
+	r := requester{}
 	if err := tx.QueryRow(ctx, queryGet, argsGet).Scan(
 		&statusBefore.X,
 		&statusBefore.Y,
 		&statusBefore.Z,
+		&statusBefore.Gen[0],
+		&statusBefore.Gen[1],
+		&statusBefore.Gen[2],
+		&statusBefore.ClientID,
+		&statusBefore.ClientName,
+		&statusBefore.RestartStrategy,
+		&r.cpu,
+		&r.mem,
+		&r.priority,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", op, storage.ErrStatusNotFound)
@@ -321,6 +490,28 @@ func (s *Storage) UpdateStatus(ctx context.Context, id int, p api.Status) (*mode
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	status := &models.Status{
+		ID: id,
+		X:  *p.X,
+		Y:  *p.Y,
+		Z:  *p.Z,
+
+		ClientID:   statusBefore.ClientID,
+		ClientName: statusBefore.ClientName,
+
+		Gen:             statusBefore.Gen,
+		RestartStrategy: statusBefore.RestartStrategy,
+	}
+
+	// Выключение подов допускается и при нехватке ресурсов
+	var preempted []models.PodOperation
+	if status.EnabledPods() > statusBefore.EnabledPods() {
+		preempted, err = s.admit(ctx, tx, status, r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	queryUpdate := `
 		update watcher.status
 		set (
@@ -349,9 +540,165 @@ func (s *Storage) UpdateStatus(ctx context.Context, id int, p api.Status) (*mode
 		return nil, fmt.Errorf("%s: %w", op, storage.ErrStatusNotFound)
 	}
 
+	ops, err := models.UpdateOperations(s.namer, status, statusBefore, needRestart)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if status.Gen != statusBefore.Gen {
+		if err := saveGenerations(ctx, tx, status); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	ops = append(preempted, ops...)
+	if err := insertOperations(ctx, tx, ops); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return statusBefore, nil
+	return ops, nil
+}
+
+var _ watcher.Storage = (*Storage)(nil)
+
+// GetStatuses возвращает все статусы.
+func (s *Storage) GetStatuses(ctx context.Context) ([]models.Status, error) {
+	const op = "storage.postgres.GetStatuses"
+
+	query := `
+		select
+			s.id,
+			s."X",
+			s."Y",
+			s."Z",
+			s."X_gen",
+			s."Y_gen",
+			s."Z_gen",
+			c.id,
+			c.name
+		from watcher.status s
+		join watcher.clients c on c.id = s.client_id
+		order by s.id;
+	`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	statuses, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Status, error) {
+		status := models.Status{}
+		err := row.Scan(
+			&status.ID,
+			&status.X,
+			&status.Y,
+			&status.Z,
+			&status.Gen[0],
+			&status.Gen[1],
+			&status.Gen[2],
+			&status.ClientID,
+			&status.ClientName,
+		)
+		return status, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return statuses, nil
+}
+
+// GetStatus возвращает статус.
+func (s *Storage) GetStatus(ctx context.Context, id int) (*models.Status, error) {
+	const op = "storage.postgres.GetStatus"
+
+	query := `
+		select
+			s.id,
+			s."X",
+			s."Y",
+			s."Z",
+			s."X_gen",
+			s."Y_gen",
+			s."Z_gen",
+			c.id,
+			c.name
+		from watcher.status s
+		join watcher.clients c on c.id = s.client_id
+		where s.id = @id;
+	`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	status := &models.Status{}
+	if err := s.pool.QueryRow(ctx, query, args).Scan(
+		&status.ID,
+		&status.X,
+		&status.Y,
+		&status.Z,
+		&status.Gen[0],
+		&status.Gen[1],
+		&status.Gen[2],
+		&status.ClientID,
+		&status.ClientName,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrStatusNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return status, nil
+}
+
+// GetClientStatus возвращает статус клиента.
+func (s *Storage) GetClientStatus(ctx context.Context, clientID int) (*models.Status, error) {
+	const op = "storage.postgres.GetClientStatus"
+
+	query := `
+		select
+			s.id,
+			coalesce(s."X", false),
+			coalesce(s."Y", false),
+			coalesce(s."Z", false),
+			coalesce(s."X_gen", 0),
+			coalesce(s."Y_gen", 0),
+			coalesce(s."Z_gen", 0),
+			c.id,
+			c.name
+		from watcher.clients c
+		left join watcher.status s on s.client_id = c.id
+		where c.id = @client_id;
+	`
+	args := pgx.NamedArgs{
+		"client_id": clientID,
+	}
+
+	var id *int
+	status := &models.Status{}
+	if err := s.pool.QueryRow(ctx, query, args).Scan(
+		&id,
+		&status.X,
+		&status.Y,
+		&status.Z,
+		&status.Gen[0],
+		&status.Gen[1],
+		&status.Gen[2],
+		&status.ClientID,
+		&status.ClientName,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrClientNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if id == nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrStatusNotFound)
+	}
+	status.ID = *id
+
+	return status, nil
 }
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/korikhin/pod-sync/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RestartPods регистрирует операции перезапуска включённых подов клиентов,
+// соответствующих фильтру.
+// Возвращает зарегистрированные операции и возможную ошибку.
+func (s *Storage) RestartPods(ctx context.Context, f models.RestartFilter) ([]models.PodOperation, error) {
+	const op = "storage.postgres.RestartPods"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	ops, err := restartPods(ctx, tx, s.namer, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ops, nil
+}
+
+// restartPods регистрирует в рамках транзакции операции перезапуска
+// включённых подов клиентов, соответствующих фильтру.
+func restartPods(ctx context.Context, tx pgx.Tx, namer *models.PodNamer, f models.RestartFilter) ([]models.PodOperation, error) {
+	query := `
+		select
+			s.id,
+			s."X",
+			s."Y",
+			s."Z",
+			s."X_gen",
+			s."Y_gen",
+			s."Z_gen",
+			c.id,
+			c.name,
+			c.restart_strategy
+		from watcher.status s
+		join watcher.clients c on c.id = s.client_id
+		where (@image = '' or c.image = @image)
+			and (@version = 0 or c.version = @version)
+			and (@client_ids::integer[] is null or c.id = any(@client_ids))
+		order by s.id
+		for update of s;
+	`
+	args := pgx.NamedArgs{
+		"image":      f.Image,
+		"version":    f.Version,
+		"client_ids": f.ClientIDs,
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Status, error) {
+		status := models.Status{}
+		err := row.Scan(
+			&status.ID,
+			&status.X,
+			&status.Y,
+			&status.Z,
+			&status.Gen[0],
+			&status.Gen[1],
+			&status.Gen[2],
+			&status.ClientID,
+			&status.ClientName,
+			&status.RestartStrategy,
+		)
+		return status, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]models.PodOperation, 0)
+	for i := range statuses {
+		gen := statuses[i].Gen
+		restart, err := models.RestartOperations(namer, &statuses[i], f.Types)
+		if err != nil {
+			return nil, err
+		}
+		if statuses[i].Gen != gen {
+			if err := saveGenerations(ctx, tx, &statuses[i]); err != nil {
+				return nil, err
+			}
+		}
+		ops = append(ops, restart...)
+	}
+
+	if err := insertOperations(ctx, tx, ops); err != nil {
+		return nil, err
+	}
+
+	return ops, nil
+}
+
+// saveGenerations сохраняет поколения подов статуса,
+// изменённые перезапуском surge.
+func saveGenerations(ctx context.Context, tx pgx.Tx, status *models.Status) error {
+	query := `
+		update watcher.status
+		set (
+			"X_gen",
+			"Y_gen",
+			"Z_gen"
+		) = (
+			@X_gen,
+			@Y_gen,
+			@Z_gen
+		)
+		where id = @id;
+	`
+	args := pgx.NamedArgs{
+		"id":    status.ID,
+		"X_gen": status.Gen[0],
+		"Y_gen": status.Gen[1],
+		"Z_gen": status.Gen[2],
+	}
+
+	_, err := tx.Exec(ctx, query, args)
+	return err
+}
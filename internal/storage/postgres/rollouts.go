@@ -0,0 +1,578 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/server/middleware/request"
+	"github.com/korikhin/pod-sync/internal/storage"
+	"github.com/korikhin/pod-sync/internal/watcher"
+
+	codes "github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Столбцы выката со статистикой текущей волны.
+// Неудачной считается операция, хотя бы одна попытка которой не удалась.
+const rolloutColumns = `
+	r.id,
+	r.image,
+	r.version,
+	coalesce(r.selector_image, ''),
+	coalesce(r.selector_version, 0),
+	r.selector_client_ids,
+	r.batch_size,
+	extract(epoch from r.pause)::bigint,
+	r.max_failure_rate,
+	r.state,
+	coalesce(r.reason, ''),
+	r.wave,
+	(
+		select count(*)
+		from watcher.rollout_clients rc
+		where rc.rollout_id = r.id
+	),
+	(
+		select count(*)
+		from watcher.rollout_clients rc
+		where rc.rollout_id = r.id
+			and rc.wave is not null
+	),
+	r.wave_ops,
+	(
+		select count(distinct l.operation_id)
+		from watcher.operation_log l
+		where l.request_id = r.request_id
+			and l.result = any(@failed::varchar[])
+	),
+	(
+		select count(*)
+		from watcher.operations o
+		where o.request_id = r.request_id
+			and o.state in (@pending, @running)
+	),
+	r.wave_started_at,
+	r.next_wave_at,
+	r.created_at,
+	r.updated_at
+`
+
+// rolloutArgs дополняет аргументы запроса аргументами столбцов выката.
+func rolloutArgs(args pgx.NamedArgs) pgx.NamedArgs {
+	args["failed"] = []string{watcher.OutcomeRetrying, watcher.OutcomeDead}
+	args["pending"] = opStatePending
+	args["running"] = opStateRunning
+	return args
+}
+
+func scanRollout(row pgx.CollectableRow) (models.Rollout, error) {
+	r := models.Rollout{}
+	var seconds int64
+	err := row.Scan(
+		&r.ID,
+		&r.Image,
+		&r.Version,
+		&r.Selector.Image,
+		&r.Selector.Version,
+		&r.Selector.ClientIDs,
+		&r.BatchSize,
+		&seconds,
+		&r.MaxFailureRate,
+		&r.State,
+		&r.Reason,
+		&r.Wave,
+		&r.Clients,
+		&r.Updated,
+		&r.Stats.Total,
+		&r.Stats.Failed,
+		&r.Stats.Pending,
+		&r.WaveStartedAt,
+		&r.NextWaveAt,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	r.Pause = time.Duration(seconds) * time.Second
+	return r, err
+}
+
+// CreateRollout создаёт выкат и отбирает его клиентов.
+// Клиенты, уже использующие образ и версию выката, не отбираются.
+// Выкат без клиентов сразу считается завершённым.
+// Возвращает ErrRolloutRunning, если выполняется другой выкат.
+func (s *Storage) CreateRollout(ctx context.Context, r models.Rollout) (*models.Rollout, error) {
+	const op = "storage.postgres.CreateRollout"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		insert into watcher.rollouts (
+			image,
+			version,
+			selector_image,
+			selector_version,
+			selector_client_ids,
+			batch_size,
+			pause,
+			max_failure_rate
+		) values (
+			@image,
+			@version,
+			nullif(@selector_image, ''),
+			nullif(@selector_version::smallint, 0),
+			@selector_client_ids::integer[],
+			@batch_size,
+			@pause::interval,
+			@max_failure_rate
+		)
+		returning id;
+	`
+	args := pgx.NamedArgs{
+		"image":               r.Image,
+		"version":             r.Version,
+		"selector_image":      r.Selector.Image,
+		"selector_version":    r.Selector.Version,
+		"selector_client_ids": r.Selector.ClientIDs,
+		"batch_size":          r.BatchSize,
+		"pause":               r.Pause,
+		"max_failure_rate":    r.MaxFailureRate,
+	}
+
+	var id int64
+	if err := tx.QueryRow(ctx, query, args).Scan(&id); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == codes.UniqueViolation {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrRolloutRunning)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	queryClients := `
+		insert into watcher.rollout_clients (
+			rollout_id,
+			client_id
+		)
+		select @rollout_id, c.id
+		from watcher.clients c
+		where (@selector_image = '' or c.image = @selector_image)
+			and (@selector_version = 0 or c.version = @selector_version)
+			and (@selector_client_ids::integer[] is null or c.id = any(@selector_client_ids))
+			and (c.image <> @image or c.version <> @version);
+	`
+	argsClients := pgx.NamedArgs{
+		"rollout_id":          id,
+		"image":               r.Image,
+		"version":             r.Version,
+		"selector_image":      r.Selector.Image,
+		"selector_version":    r.Selector.Version,
+		"selector_client_ids": r.Selector.ClientIDs,
+	}
+
+	tag, err := tx.Exec(ctx, queryClients, argsClients)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		if err := setRolloutState(ctx, tx, id, models.RolloutCompleted, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	created, err := getRollout(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// GetRollouts возвращает выкаты в порядке создания.
+func (s *Storage) GetRollouts(ctx context.Context) ([]models.Rollout, error) {
+	const op = "storage.postgres.GetRollouts"
+
+	query := `
+		select ` + rolloutColumns + `
+		from watcher.rollouts r
+		order by r.id;
+	`
+
+	rows, err := s.pool.Query(ctx, query, rolloutArgs(pgx.NamedArgs{}))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rollouts, err := pgx.CollectRows(rows, scanRollout)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rollouts, nil
+}
+
+// GetRollout возвращает выкат со статистикой текущей волны.
+func (s *Storage) GetRollout(ctx context.Context, id int64) (*models.Rollout, error) {
+	const op = "storage.postgres.GetRollout"
+
+	query := `
+		select ` + rolloutColumns + `
+		from watcher.rollouts r
+		where r.id = @id;
+	`
+	args := rolloutArgs(pgx.NamedArgs{
+		"id": id,
+	})
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r, err := pgx.CollectExactlyOneRow(rows, scanRollout)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrRolloutNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &r, nil
+}
+
+// GetRunningRollout возвращает выполняющийся выкат или nil, если его нет.
+func (s *Storage) GetRunningRollout(ctx context.Context) (*models.Rollout, error) {
+	const op = "storage.postgres.GetRunningRollout"
+
+	query := `
+		select ` + rolloutColumns + `
+		from watcher.rollouts r
+		where r.state = @running_rollout;
+	`
+	args := rolloutArgs(pgx.NamedArgs{
+		"running_rollout": models.RolloutRunning,
+	})
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r, err := pgx.CollectExactlyOneRow(rows, scanRollout)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &r, nil
+}
+
+// CancelRollout отменяет выполняющийся выкат.
+// Клиенты, обновлённые начатыми волнами, и их операции не затрагиваются.
+func (s *Storage) CancelRollout(ctx context.Context, id int64) error {
+	const op = "storage.postgres.CancelRollout"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		select state
+		from watcher.rollouts
+		where id = @id
+		for update;
+	`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	var state string
+	if err := tx.QueryRow(ctx, query, args).Scan(&state); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrRolloutNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if state != models.RolloutRunning {
+		return fmt.Errorf("%s: %w", op, storage.ErrRolloutNotRunning)
+	}
+
+	if err := setRolloutState(ctx, tx, id, models.RolloutCancelled, "cancelled via API"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// StartRolloutWave начинает следующую волну выката: обновляет образ и версию
+// очередных клиентов и регистрирует перезапуск их включённых подов.
+// Операции волны помечаются идентификатором запроса волны.
+// Если выкат остановлен или волна уже начата, ничего не делает.
+//
+// Ресурсы кластера не проверяются: выкат меняет только образ и версию,
+// а ресурсы, требуемые подами клиентов, остаются прежними.
+//
+// Возвращает зарегистрированные операции и возможную ошибку.
+func (s *Storage) StartRolloutWave(ctx context.Context, r models.Rollout) ([]models.PodOperation, error) {
+	const op = "storage.postgres.StartRolloutWave"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	queryLock := `
+		select
+			state,
+			wave
+		from watcher.rollouts
+		where id = @id
+		for update;
+	`
+	argsLock := pgx.NamedArgs{
+		"id": r.ID,
+	}
+
+	var state string
+	var wave int
+	if err := tx.QueryRow(ctx, queryLock, argsLock).Scan(&state, &wave); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrRolloutNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if state != models.RolloutRunning || wave != r.Wave {
+		return nil, nil
+	}
+
+	wave++
+	requestID := r.WaveRequestID(wave)
+
+	queryBatch := `
+		update watcher.rollout_clients
+		set wave = @wave
+		where rollout_id = @id
+			and client_id in (
+				select client_id
+				from watcher.rollout_clients
+				where rollout_id = @id
+					and wave is null
+				order by client_id
+				limit @batch_size
+			)
+		returning client_id;
+	`
+	argsBatch := pgx.NamedArgs{
+		"id":         r.ID,
+		"wave":       wave,
+		"batch_size": r.BatchSize,
+	}
+
+	rows, err := tx.Query(ctx, queryBatch, argsBatch)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	batch, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Удалённые клиенты пропускаются
+	queryClients := `
+		update watcher.clients
+		set (
+			image,
+			version,
+			updated_at
+		) = (
+			@image,
+			@version,
+			timezone('UTC', now())
+		)
+		where id = any(@ids)
+			and (image <> @image or version <> @version)
+		returning id;
+	`
+	argsClients := pgx.NamedArgs{
+		"ids":     batch,
+		"image":   r.Image,
+		"version": r.Version,
+	}
+
+	rows, err = tx.Query(ctx, queryClients, argsClients)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	updated, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Пустой список клиентов фильтра перезапуска означает всех клиентов
+	ops := make([]models.PodOperation, 0)
+	if len(updated) > 0 {
+		ctx := context.WithValue(ctx, request.RequestKey, requestID)
+		f := models.RestartFilter{ClientIDs: updated}
+		if ops, err = restartPods(ctx, tx, s.namer, f); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	queryWave := `
+		update watcher.rollouts
+		set (
+			wave,
+			wave_ops,
+			request_id,
+			wave_started_at,
+			next_wave_at,
+			updated_at
+		) = (
+			@wave,
+			@wave_ops,
+			@request_id,
+			timezone('UTC', now()),
+			null,
+			timezone('UTC', now())
+		)
+		where id = @id;
+	`
+	argsWave := pgx.NamedArgs{
+		"id":         r.ID,
+		"wave":       wave,
+		"wave_ops":   len(ops),
+		"request_id": requestID,
+	}
+
+	if _, err := tx.Exec(ctx, queryWave, argsWave); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ops, nil
+}
+
+// SetRolloutNextWave задаёт время начала следующей волны выполняющегося выката.
+func (s *Storage) SetRolloutNextWave(ctx context.Context, id int64, at time.Time) error {
+	const op = "storage.postgres.SetRolloutNextWave"
+
+	query := `
+		update watcher.rollouts
+		set (
+			next_wave_at,
+			updated_at
+		) = (
+			@next_wave_at,
+			timezone('UTC', now())
+		)
+		where id = @id
+			and state = @running_rollout;
+	`
+	args := pgx.NamedArgs{
+		"id":              id,
+		"next_wave_at":    at.UTC(),
+		"running_rollout": models.RolloutRunning,
+	}
+
+	if _, err := s.pool.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// FinishRollout завершает или останавливает выполняющийся выкат.
+func (s *Storage) FinishRollout(ctx context.Context, id int64, state, reason string) error {
+	const op = "storage.postgres.FinishRollout"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setRolloutState(ctx, tx, id, state, reason); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// getRollout возвращает выкат в рамках транзакции.
+func getRollout(ctx context.Context, tx pgx.Tx, id int64) (*models.Rollout, error) {
+	query := `
+		select ` + rolloutColumns + `
+		from watcher.rollouts r
+		where r.id = @id;
+	`
+	args := rolloutArgs(pgx.NamedArgs{
+		"id": id,
+	})
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := pgx.CollectExactlyOneRow(rows, scanRollout)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrRolloutNotFound
+		}
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// setRolloutState переводит выполняющийся выкат в состояние state.
+func setRolloutState(ctx context.Context, tx pgx.Tx, id int64, state, reason string) error {
+	query := `
+		update watcher.rollouts
+		set (
+			state,
+			reason,
+			updated_at
+		) = (
+			@state,
+			nullif(@reason, ''),
+			timezone('UTC', now())
+		)
+		where id = @id
+			and state = @running_rollout;
+	`
+	args := pgx.NamedArgs{
+		"id":              id,
+		"state":           state,
+		"reason":          reason,
+		"running_rollout": models.RolloutRunning,
+	}
+
+	_, err := tx.Exec(ctx, query, args)
+	return err
+}
@@ -0,0 +1,321 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/storage"
+
+	codes "github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const scheduleColumns = `
+	id,
+	status_id,
+	pods,
+	run_at,
+	coalesce(turn_on, false),
+	coalesce(cron, ''),
+	coalesce(extract(epoch from duration)::bigint, 0),
+	coalesce(timezone, ''),
+	last_on,
+	applied_at,
+	created_at
+`
+
+func scanSchedule(row pgx.CollectableRow) (models.Schedule, error) {
+	sc := models.Schedule{}
+	var at *time.Time
+	var seconds int64
+	err := row.Scan(
+		&sc.ID,
+		&sc.StatusID,
+		&sc.Types,
+		&at,
+		&sc.On,
+		&sc.Cron,
+		&seconds,
+		&sc.Timezone,
+		&sc.LastOn,
+		&sc.AppliedAt,
+		&sc.CreatedAt,
+	)
+	if at != nil {
+		sc.At = *at
+	}
+	sc.Duration = time.Duration(seconds) * time.Second
+	return sc, err
+}
+
+// AddSchedule добавляет расписание статуса.
+func (s *Storage) AddSchedule(ctx context.Context, sc models.Schedule) (*models.Schedule, error) {
+	const op = "storage.postgres.AddSchedule"
+
+	query := `
+		insert into watcher.schedules (
+			status_id,
+			pods,
+			run_at,
+			turn_on,
+			cron,
+			duration,
+			timezone
+		) values (
+			@status_id,
+			@pods,
+			@run_at,
+			@turn_on,
+			nullif(@cron, ''),
+			@duration::interval,
+			nullif(@timezone, '')
+		)
+		returning ` + scheduleColumns + `;
+	`
+	args := pgx.NamedArgs{
+		"status_id": sc.StatusID,
+		"pods":      sc.Types,
+		"run_at":    nil,
+		"turn_on":   nil,
+		"cron":      sc.Cron,
+		"duration":  nil,
+		"timezone":  sc.Timezone,
+	}
+	if sc.IsWindow() {
+		args["duration"] = sc.Duration
+	} else {
+		args["run_at"] = sc.At.UTC()
+		args["turn_on"] = sc.On
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, err := pgx.CollectExactlyOneRow(rows, scanSchedule)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == codes.ForeignKeyViolation {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrStatusNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &created, nil
+}
+
+// GetStatusSchedules возвращает расписания статуса.
+func (s *Storage) GetStatusSchedules(ctx context.Context, statusID int) ([]models.Schedule, error) {
+	const op = "storage.postgres.GetStatusSchedules"
+
+	query := `
+		select ` + scheduleColumns + `
+		from watcher.schedules
+		where status_id = @status_id
+		order by id;
+	`
+	args := pgx.NamedArgs{
+		"status_id": statusID,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	schedules, err := pgx.CollectRows(rows, scanSchedule)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return schedules, nil
+}
+
+// DeleteSchedule удаляет расписание.
+func (s *Storage) DeleteSchedule(ctx context.Context, id int) error {
+	const op = "storage.postgres.DeleteSchedule"
+
+	query := `
+		delete from watcher.schedules
+		where id = @id;
+	`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+
+	tag, err := s.pool.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrScheduleNotFound)
+	}
+
+	return nil
+}
+
+// GetPendingSchedules возвращает расписания, которые могут потребовать
+// применения: не применённые разовые и все повторяющиеся.
+func (s *Storage) GetPendingSchedules(ctx context.Context) ([]models.Schedule, error) {
+	const op = "storage.postgres.GetPendingSchedules"
+
+	query := `
+		select ` + scheduleColumns + `
+		from watcher.schedules
+		where cron is not null
+			or (applied_at is null and run_at <= timezone('UTC', now()))
+		order by id;
+	`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	schedules, err := pgx.CollectRows(rows, scanSchedule)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return schedules, nil
+}
+
+// ApplySchedule включает или выключает поды расписания и отмечает его применённым.
+// Регистрирует соответствующие операции с подами.
+//
+// Включение подов проверяет ресурсы кластера так же, как UpdateStatus.
+// Если ресурсов недостаточно, возвращает *models.CapacityError,
+// а расписание остаётся неприменённым.
+//
+// Возвращает зарегистрированные операции и возможную ошибку.
+func (s *Storage) ApplySchedule(ctx context.Context, sc models.Schedule, on bool) ([]models.PodOperation, error) {
+	const op = "storage.postgres.ApplySchedule"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокировка ресурсов кластера берётся до блокировки строки статуса
+	if err := s.lockCapacity(ctx, tx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	queryGet := `
+		select
+			s."X",
+			s."Y",
+			s."Z",
+			s."X_gen",
+			s."Y_gen",
+			s."Z_gen",
+			c.id,
+			c.name,
+			c.cpu,
+			c.mem,
+			c.priority
+		from watcher.status s
+		join watcher.clients c on c.id = s.client_id
+		where s.id = @id
+		for update of s;
+	`
+	argsGet := pgx.NamedArgs{
+		"id": sc.StatusID,
+	}
+
+	statusBefore := &models.Status{ID: sc.StatusID}
+	r := requester{}
+	if err := tx.QueryRow(ctx, queryGet, argsGet).Scan(
+		&statusBefore.X,
+		&statusBefore.Y,
+		&statusBefore.Z,
+		&statusBefore.Gen[0],
+		&statusBefore.Gen[1],
+		&statusBefore.Gen[2],
+		&statusBefore.ClientID,
+		&statusBefore.ClientName,
+		&r.cpu,
+		&r.mem,
+		&r.priority,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrStatusNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	status := sc.Apply(*statusBefore, on)
+
+	var preempted []models.PodOperation
+	if status.EnabledPods() > statusBefore.EnabledPods() {
+		preempted, err = s.admit(ctx, tx, &status, r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	queryUpdate := `
+		update watcher.status
+		set (
+			"X",
+			"Y",
+			"Z"
+		) = (
+			@X,
+			@Y,
+			@Z
+		)
+		where id = @id;
+	`
+	argsUpdate := pgx.NamedArgs{
+		"id": status.ID,
+		"X":  status.X,
+		"Y":  status.Y,
+		"Z":  status.Z,
+	}
+
+	if _, err := tx.Exec(ctx, queryUpdate, argsUpdate); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ops, err := models.UpdateOperations(s.namer, &status, statusBefore, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	ops = append(preempted, ops...)
+	if err := insertOperations(ctx, tx, ops); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	queryApplied := `
+		update watcher.schedules
+		set (
+			last_on,
+			applied_at
+		) = (
+			@on,
+			timezone('UTC', now())
+		)
+		where id = @id;
+	`
+	argsApplied := pgx.NamedArgs{
+		"id": sc.ID,
+		"on": on,
+	}
+
+	if _, err := tx.Exec(ctx, queryApplied, argsApplied); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ops, nil
+}
@@ -0,0 +1,217 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExpirePods выключает поды, время жизни которых истекло.
+// Время жизни, заданное для статуса, действует вместо ttl.
+// Регистрирует операции удаления подов.
+// Возвращает зарегистрированные операции и возможную ошибку.
+func (s *Storage) ExpirePods(ctx context.Context, ttl models.PodTTL) ([]models.PodOperation, error) {
+	const op = "storage.postgres.ExpirePods"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	queryGet := `
+		select
+			s.id,
+			s."X",
+			s."Y",
+			s."Z",
+			s."X_gen",
+			s."Y_gen",
+			s."Z_gen",
+			c.id,
+			c.name,
+			e.x,
+			e.y,
+			e.z
+		from watcher.status s
+		join watcher.clients c on c.id = s.client_id
+		cross join lateral (
+			select
+				coalesce(s."X_since" + nullif(coalesce(s.ttl, @ttl_x::interval), '0') <= timezone('UTC', now()), false) as x,
+				coalesce(s."Y_since" + nullif(coalesce(s.ttl, @ttl_y::interval), '0') <= timezone('UTC', now()), false) as y,
+				coalesce(s."Z_since" + nullif(coalesce(s.ttl, @ttl_z::interval), '0') <= timezone('UTC', now()), false) as z
+		) e
+		where e.x or e.y or e.z
+		order by s.id
+		for update of s;
+	`
+	argsGet := pgx.NamedArgs{
+		"ttl_x": ttl.X,
+		"ttl_y": ttl.Y,
+		"ttl_z": ttl.Z,
+	}
+
+	type expiry struct {
+		status models.Status
+		types  []string
+	}
+
+	rows, err := tx.Query(ctx, queryGet, argsGet)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	expired, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (expiry, error) {
+		e := expiry{}
+		var flags [len(models.PodTypes)]bool
+		err := row.Scan(
+			&e.status.ID,
+			&e.status.X,
+			&e.status.Y,
+			&e.status.Z,
+			&e.status.Gen[0],
+			&e.status.Gen[1],
+			&e.status.Gen[2],
+			&e.status.ClientID,
+			&e.status.ClientName,
+			&flags[0],
+			&flags[1],
+			&flags[2],
+		)
+		e.types = podTypes(flags)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	queryUpdate := `
+		update watcher.status
+		set (
+			"X",
+			"Y",
+			"Z"
+		) = (
+			@X,
+			@Y,
+			@Z
+		)
+		where id = @id;
+	`
+
+	ops := make([]models.PodOperation, 0, len(expired))
+	for _, e := range expired {
+		status := models.Expired(e.status, e.types)
+
+		argsUpdate := pgx.NamedArgs{
+			"id": status.ID,
+			"X":  status.X,
+			"Y":  status.Y,
+			"Z":  status.Z,
+		}
+		if _, err := tx.Exec(ctx, queryUpdate, argsUpdate); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		statusOps, err := models.UpdateOperations(s.namer, &status, &e.status, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ops = append(ops, statusOps...)
+	}
+
+	if err := insertOperations(ctx, tx, ops); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ops, nil
+}
+
+// RenewStatus продлевает время жизни включённых подов статуса.
+// Если types заданы, продлеваются только поды указанных типов.
+// Возвращает типы продлённых подов и возможную ошибку.
+func (s *Storage) RenewStatus(ctx context.Context, id int, types []string) ([]string, error) {
+	const op = "storage.postgres.RenewStatus"
+
+	query := `
+		update watcher.status
+		set (
+			"X_since",
+			"Y_since",
+			"Z_since"
+		) = (
+			case when "X" and (@types::varchar[] is null or 'X' = any(@types::varchar[])) then timezone('UTC', now()) else "X_since" end,
+			case when "Y" and (@types::varchar[] is null or 'Y' = any(@types::varchar[])) then timezone('UTC', now()) else "Y_since" end,
+			case when "Z" and (@types::varchar[] is null or 'Z' = any(@types::varchar[])) then timezone('UTC', now()) else "Z_since" end
+		)
+		where id = @id
+		returning
+			"X" and (@types::varchar[] is null or 'X' = any(@types::varchar[])),
+			"Y" and (@types::varchar[] is null or 'Y' = any(@types::varchar[])),
+			"Z" and (@types::varchar[] is null or 'Z' = any(@types::varchar[]));
+	`
+	if len(types) == 0 {
+		types = nil
+	}
+	args := pgx.NamedArgs{
+		"id":    id,
+		"types": types,
+	}
+
+	var flags [len(models.PodTypes)]bool
+	if err := s.pool.QueryRow(ctx, query, args).Scan(&flags[0], &flags[1], &flags[2]); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrStatusNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return podTypes(flags), nil
+}
+
+// podTypes возвращает типы подов, отмеченных во flags в порядке PodTypes.
+func podTypes(flags [len(models.PodTypes)]bool) []string {
+	types := make([]string, 0, len(flags))
+	for i, podType := range models.PodTypes {
+		if flags[i] {
+			types = append(types, podType)
+		}
+	}
+	return types
+}
+
+// SetStatusTTL задаёт время жизни подов статуса.
+// Если ttl равен nil, действует время жизни по типам подов.
+func (s *Storage) SetStatusTTL(ctx context.Context, id int, ttl *time.Duration) error {
+	const op = "storage.postgres.SetStatusTTL"
+
+	query := `
+		update watcher.status
+		set ttl = @ttl::interval
+		where id = @id;
+	`
+	args := pgx.NamedArgs{
+		"id":  id,
+		"ttl": ttl,
+	}
+
+	tag, err := s.pool.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrStatusNotFound)
+	}
+
+	return nil
+}
@@ -10,4 +10,9 @@ var (
 	ErrConnectionUnauthorized = errors.New("connection unauthorized")
 	ErrClientNotFound         = errors.New("client not found")
 	ErrStatusNotFound         = errors.New("status not found")
+	ErrOperationNotFound      = errors.New("operation not found")
+	ErrScheduleNotFound       = errors.New("schedule not found")
+	ErrRolloutNotFound        = errors.New("rollout not found")
+	ErrRolloutRunning         = errors.New("another rollout is running")
+	ErrRolloutNotRunning      = errors.New("rollout is not running")
 )
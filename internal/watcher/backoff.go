@@ -0,0 +1,23 @@
+package watcher
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// backoff возвращает задержку перед очередной попыткой выполнения операции.
+// Задержка растёт экспоненциально с каждой попыткой и ограничена limit.
+// Половина задержки выбирается случайно, чтобы повторные попытки
+// не выполнялись одновременно.
+func backoff(attempts int, base, limit time.Duration) time.Duration {
+	d := limit
+	if attempts > 0 && attempts < 32 {
+		if e := base << (attempts - 1); e > 0 && e < limit {
+			d = e
+		}
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
@@ -0,0 +1,83 @@
+package watcher
+
+import (
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+)
+
+type gcOptions struct {
+	enabled      bool
+	gracePeriod  time.Duration
+	maxDeletions int
+	allowlist    []string
+}
+
+// collectOrphans возвращает операции удаления подов, оставшихся от удалённых
+// клиентов: поды соответствуют правилу именования, но их клиент отсутствует.
+// Также удаляются поды статусов с устаревшими именами.
+//
+// Под удаляется, только если остаётся бесхозным дольше gracePeriod.
+// Поды, соответствующие шаблонам allowlist, и поды, для которых в очереди
+// есть операции, не затрагиваются. За один вызов удаляется не более
+// maxDeletions подов (0 — без ограничений).
+//
+// Вызывается только из основного цикла Watcher'а.
+func (w *Watcher) collectOrphans(orphans []string, queued map[string]struct{}) []models.PodOperation {
+	const op = "watcher.collectOrphans"
+
+	log := w.log.With(sl.Operation(op))
+
+	now := time.Now()
+	seen := make(map[string]time.Time, len(orphans))
+	ops := make([]models.PodOperation, 0)
+	skipped := 0
+
+	for _, p := range orphans {
+		ref, ok := w.namer.Parse(p)
+		if !ok || w.allowed(p) {
+			continue
+		}
+		if _, ok := queued[p]; ok {
+			continue
+		}
+
+		first, ok := w.orphans[p]
+		if !ok {
+			first = now
+			log.Info("orphan pod detected", slog.String("pod_id", p))
+		}
+		seen[p] = first
+
+		if now.Sub(first) < w.opts.gc.gracePeriod {
+			continue
+		}
+		if w.opts.gc.maxDeletions > 0 && len(ops) >= w.opts.gc.maxDeletions {
+			skipped++
+			continue
+		}
+		po := models.OpDelete(p, ref.StatusID)
+		po.ClientID = ref.ClientID
+		ops = append(ops, po)
+	}
+
+	w.orphans = seen
+
+	if skipped > 0 {
+		log.Warn("orphan deletions limit reached", slog.Int("deferred", skipped))
+	}
+	return ops
+}
+
+// allowed сообщает, защищён ли под от удаления шаблонами allowlist.
+func (w *Watcher) allowed(podID string) bool {
+	for _, pattern := range w.opts.gc.allowlist {
+		if ok, _ := path.Match(pattern, podID); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package watcher
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/models"
+
+	"github.com/korikhin/pod-sync/pkg/deployer/mocks"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReconcileStalePods(t *testing.T) {
+	statuses := []models.Status{
+		{ID: 5, ClientID: 1, X: true, Y: true, Gen: [3]int{0, 1, 0}},
+	}
+	// Y-5 и Y-5-g9 — поды статуса 5 с устаревшими именами, 42 — удалённый статус
+	pods := []string{"X-5", "Y-5", "Y-5-g1", "Y-5-g9", "Z-42"}
+
+	tests := []struct {
+		name    string
+		gc      gcOptions
+		queued  []string
+		seen    map[string]time.Time
+		deleted []string
+	}{
+		{
+			name: "gc disabled",
+			gc:   gcOptions{enabled: false},
+		},
+		{
+			name:    "gc enabled",
+			gc:      gcOptions{enabled: true},
+			deleted: []string{"Y-5", "Y-5-g9", "Z-42"},
+		},
+		{
+			name:    "allowlist",
+			gc:      gcOptions{enabled: true, allowlist: []string{"Y-5-g*"}},
+			deleted: []string{"Y-5", "Z-42"},
+		},
+		{
+			name:    "queued",
+			gc:      gcOptions{enabled: true},
+			queued:  []string{"Y-5"},
+			deleted: []string{"Y-5-g9", "Z-42"},
+		},
+		{
+			name:    "grace period",
+			gc:      gcOptions{enabled: true, gracePeriod: time.Hour},
+			seen:    map[string]time.Time{"Y-5": time.Now().Add(-2 * time.Hour)},
+			deleted: []string{"Y-5"},
+		},
+		{
+			name:    "max deletions",
+			gc:      gcOptions{enabled: true, maxDeletions: 1},
+			deleted: []string{"Z-42"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &mocks.Deployer{}
+			d.On("GetPodList").Return(pods, nil)
+			d.On("DeletePod", mock.Anything).Return(nil)
+
+			s := newFakeStorage()
+			s.queued = tt.queued
+
+			w := &Watcher{
+				log:     discardLogger(),
+				s:       s,
+				d:       d,
+				orphans: tt.seen,
+				stopCh:  make(chan struct{}),
+				opts:    watcherOptions{workers: 1, gc: tt.gc},
+			}
+
+			results, err := w.reconcileStatuses(context.Background(), statuses, tt.gc.enabled)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			deleted := make([]string, 0)
+			for _, r := range results {
+				if r.Op.Code != models.OpCodeDelete {
+					t.Errorf("unexpected operation %v", r.Op)
+					continue
+				}
+				deleted = append(deleted, r.Op.PodID)
+			}
+			slices.Sort(deleted)
+			if !slices.Equal(deleted, tt.deleted) {
+				t.Errorf("deleted = %v, want %v", deleted, tt.deleted)
+			}
+		})
+	}
+}
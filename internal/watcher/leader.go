@@ -0,0 +1,190 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+)
+
+// Leadership представляет лидерство экземпляра сервиса.
+// Синхронизацию выполняет только лидер.
+type Leadership interface {
+	// Check проверяет, что лидерство сохраняется.
+	Check(ctx context.Context) error
+
+	// Resign освобождает лидерство.
+	Resign(ctx context.Context) error
+
+	// Abort прекращает лидерство, не дожидаясь завершения синхронизации.
+	// Может вызываться параллельно с Check и Resign.
+	Abort()
+}
+
+// IsLeader сообщает, является ли экземпляр сервиса лидером.
+func (w *Watcher) IsLeader() bool {
+	if w == nil {
+		return false
+	}
+	return w.leader.Load()
+}
+
+// elect пытается получить лидерство.
+// Возвращает nil, если лидером является другой экземпляр сервиса.
+func (w *Watcher) elect() Leadership {
+	const op = "watcher.elect"
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.electionInterval)
+	defer cancel()
+
+	l, err := w.s.TryLead(ctx)
+	if err != nil {
+		w.log.Error("failed to acquire leadership", sl.Operation(op), sl.Error(err))
+		return nil
+	}
+	return l
+}
+
+// lead выполняет синхронизацию, пока экземпляр сервиса остаётся лидером.
+// Возвращает true, если получен сигнал об остановке.
+func (w *Watcher) lead(l Leadership) bool {
+	const op = "watcher.lead"
+
+	log := w.log.With(sl.Operation(op))
+
+	log.Info("leadership acquired")
+	resigned := make(chan struct{})
+	w.resigned.Store(&resigned)
+	w.leader.Store(true)
+
+	defer func() {
+		w.leader.Store(false)
+		close(resigned)
+
+		// Пока экземпляр не является лидером, бесхозные поды не отслеживаются,
+		// поэтому при следующем получении лидерства их время обнаружения
+		// отсчитывается заново
+		w.orphans = nil
+
+		ctx, cancel := context.WithTimeout(context.Background(), w.opts.electionInterval)
+		defer cancel()
+
+		if err := l.Resign(ctx); err != nil {
+			log.Error("failed to resign leadership", sl.Error(err))
+		}
+	}()
+
+	// Проверяет лидерство перед очередным действием
+	alive := func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), w.opts.electionInterval)
+		defer cancel()
+
+		if err := l.Check(ctx); err != nil {
+			log.Warn("leadership lost", sl.Error(err))
+			return false
+		}
+		return true
+	}
+
+	// Если Watcher не остановился вовремя, лидерство прекращается,
+	// чтобы не задерживать закрытие хранилища
+	stopAbort := context.AfterFunc(w.ctx, l.Abort)
+	defer stopAbort()
+
+	// Уведомления об изменениях статусов вне API
+	ctx, cancel := context.WithCancel(w.ctx)
+	defer cancel()
+
+	changed := make(chan int, 64)
+	go w.listen(ctx, changed)
+
+	ticker := time.NewTicker(w.opts.syncInterval)
+	defer ticker.Stop()
+
+	check := time.NewTicker(w.opts.electionInterval)
+	defer check.Stop()
+
+	schedule := time.NewTicker(w.opts.scheduleInterval)
+	defer schedule.Stop()
+
+	// Операции, выбранные предыдущим лидером или до аварийного завершения
+	// и не выполненные за opts.claimTimeout, возвращаются в очередь
+	w.restore()
+
+	// Сверка при получении лидерства восстанавливает состояние
+	// после перезапуска сервиса или смены лидера
+	paused := w.checkPaused()
+	if paused {
+		log.Warn("watcher is paused")
+	} else {
+		w.sync()
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if !alive() {
+				return false
+			}
+			// Операции прежнего лидера, так и не завершённые им
+			w.restore()
+			if paused = w.checkPaused(); !paused {
+				w.sync()
+			}
+		case <-check.C:
+			if !alive() {
+				return false
+			}
+			// При возобновлении накопленные операции выполняются сразу
+			wasPaused := paused
+			paused = w.checkPaused()
+			switch {
+			case !wasPaused && paused:
+				log.Warn("watcher paused")
+			case wasPaused && !paused:
+				log.Info("watcher resumed")
+				w.sync()
+			}
+		case <-schedule.C:
+			if !alive() {
+				return false
+			}
+			// При приостановке изменения статусов регистрируются,
+			// но операции выполняются только после возобновления
+			paused = w.checkPaused()
+			w.applySchedules(!paused)
+			w.expirePods(!paused)
+			// Волны выката не начинаются при приостановке,
+			// поскольку их операции не выполняются
+			if !paused {
+				w.advanceRollout()
+			}
+		case id := <-changed:
+			if !alive() {
+				return false
+			}
+			// Изменение будет учтено сверкой после возобновления
+			if paused = w.checkPaused(); paused {
+				continue
+			}
+			log.Info("status changed externally", slog.Int("status_id", id))
+			w.reconcileNow(id)
+		case req := <-w.syncCh:
+			if !alive() {
+				req.resp <- syncResponse{err: ErrNotLeader}
+				return false
+			}
+			if paused = w.checkPaused(); paused {
+				req.resp <- syncResponse{err: ErrPaused}
+				continue
+			}
+			w.serve(req)
+		case <-w.stopCh:
+			if !w.checkPaused() {
+				w.drain()
+			}
+			return true
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+	"github.com/korikhin/pod-sync/internal/storage"
+)
+
+// listen принимает уведомления об изменении статусов вне API сервиса
+// и передаёт идентификаторы статусов в канал ids.
+// При разрыве соединения подписка возобновляется. Завершается при отмене ctx.
+func (w *Watcher) listen(ctx context.Context, ids chan<- int) {
+	const op = "watcher.listen"
+
+	log := w.log.With(sl.Operation(op))
+
+	notify := func(id int) {
+		select {
+		case ids <- id:
+		case <-ctx.Done():
+		}
+	}
+
+	for {
+		if err := w.s.ListenStatus(ctx, notify); err != nil {
+			log.Error("status notifications interrupted", sl.Error(err))
+		}
+
+		select {
+		case <-time.After(w.opts.electionInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reconcileStatus сверяет поды одного статуса.
+// Если статус удалён, его поды удаляются.
+func (w *Watcher) reconcileStatus(ctx context.Context, id int) ([]Result, error) {
+	const op = "watcher.reconcileStatus"
+
+	status, err := w.s.GetStatus(ctx, id)
+	if err != nil {
+		if !errors.Is(err, storage.ErrStatusNotFound) {
+			w.log.Error("failed to get status", sl.Operation(op), sl.Error(err))
+			return nil, err
+		}
+		status = &models.Status{ID: id}
+	}
+
+	return w.reconcileStatuses(ctx, []models.Status{*status}, false)
+}
+
+// reconcileNow выполняет сверку подов статуса вне очередного интервала.
+func (w *Watcher) reconcileNow(id int) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.syncInterval)
+	defer cancel()
+
+	w.reconcileStatus(ctx, id)
+}
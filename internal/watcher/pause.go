@@ -0,0 +1,59 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+)
+
+var ErrPaused = errors.New("watcher is paused")
+
+// Pause приостанавливает выполнение операций и сверку.
+// API продолжает принимать изменения, операции накапливаются в очереди.
+// Состояние сохраняется в хранилище и действует на все экземпляры сервиса,
+// в том числе после перезапуска. Уже начатая синхронизация завершается.
+func (w *Watcher) Pause(ctx context.Context) error {
+	if err := w.s.SetPaused(ctx, true); err != nil {
+		return err
+	}
+	w.paused.Store(true)
+	return nil
+}
+
+// Resume возобновляет выполнение операций.
+// Лидер выполняет накопленные операции и сверку не позднее,
+// чем через интервал проверки лидерства.
+func (w *Watcher) Resume(ctx context.Context) error {
+	if err := w.s.SetPaused(ctx, false); err != nil {
+		return err
+	}
+	w.paused.Store(false)
+	return nil
+}
+
+// Paused сообщает, приостановлено ли выполнение операций.
+// Если состояние не удалось получить из хранилища, возвращает последнее известное.
+func (w *Watcher) Paused(ctx context.Context) bool {
+	const op = "watcher.Paused"
+
+	if w == nil {
+		return false
+	}
+
+	paused, err := w.s.IsPaused(ctx)
+	if err != nil {
+		w.log.Error("failed to get paused state", sl.Operation(op), sl.Error(err))
+		return w.paused.Load()
+	}
+	w.paused.Store(paused)
+	return paused
+}
+
+// checkPaused получает из хранилища признак приостановки.
+func (w *Watcher) checkPaused() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.electionInterval)
+	defer cancel()
+
+	return w.Paused(ctx)
+}
@@ -0,0 +1,265 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+
+	"github.com/korikhin/pod-sync/pkg/deployer"
+)
+
+var errUnknownOperation = errors.New("unknown operation")
+
+// execute выполняет операции с подами, используя не более opts.workers
+// параллельных исполнителей. Операции над одним подом выполняются
+// последовательно в исходном порядке. Блокируется до выполнения всех операций.
+//
+// Исполнители назначаются подам в порядке первой операции над подом, поэтому
+// при занятости всех исполнителей операции клиентов с более высоким
+// приоритетом выполняются раньше.
+//
+// После сигнала об остановке новые операции не начинаются, если это
+// предписывает политика остановки.
+//
+// Если durable установлен, результат выполнения сохраняется в хранилище:
+// неудачная операция откладывается согласно backoff, а последующие операции
+// над тем же подом возвращаются в очередь. По исчерпании попыток операция
+// переносится в список dead letters.
+//
+// Возвращает исходы выполнения операций.
+func (w *Watcher) execute(ctx context.Context, ops []models.PodOperation, durable bool) []Result {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	sem := make(chan struct{}, w.opts.workers)
+	wg := sync.WaitGroup{}
+
+	results := make([]Result, 0, len(ops))
+	mu := sync.Mutex{}
+
+	collect := func(rs ...Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, rs...)
+	}
+
+	for _, group := range groupByPod(ops) {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			for i, po := range group {
+				if w.halted() {
+					collect(w.release(ctx, group[i:])...)
+					return
+				}
+				started := time.Now()
+				err := w.perform(ctx, po)
+				if notStarted(err) {
+					// Операция ожидала бюджета перезапуска или Deployer недоступен.
+					// Операции остаются в очереди и будут выбраны повторно
+					collect(w.postpone(ctx, group[i:], durable)...)
+					return
+				}
+				if !durable {
+					w.report(po, err)
+					r := Result{Op: po, Outcome: outcome(err), Err: err}
+					w.record(ctx, r, po.Attempts+1, started)
+					collect(r)
+					continue
+				}
+				r := w.settle(ctx, po, err, started)
+				collect(r)
+				if r.Outcome == OutcomeRetrying {
+					collect(w.release(ctx, group[i+1:])...)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// notStarted сообщает, что операция не выполнялась и попытка не учитывается.
+func notStarted(err error) bool {
+	return errors.Is(err, errNotStarted) || errors.Is(err, deployer.ErrUnavailable)
+}
+
+// postpone откладывает невыполненные операции.
+// Операции из очереди возвращаются в очередь хранилища,
+// операции сверки будут сформированы следующей сверкой.
+func (w *Watcher) postpone(ctx context.Context, ops []models.PodOperation, durable bool) []Result {
+	if !durable {
+		results := make([]Result, 0, len(ops))
+		for _, po := range ops {
+			results = append(results, Result{Op: po, Outcome: OutcomeReleased})
+		}
+		return results
+	}
+
+	// Контекст выполнения к этому моменту может истечь
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.opts.electionInterval)
+	defer cancel()
+
+	return w.release(ctx, ops)
+}
+
+// outcome возвращает исход выполнения операции сверки.
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeFailed
+	}
+	return OutcomeSucceeded
+}
+
+// settle сохраняет результат выполнения операции и возвращает его исход.
+// Запись в истории операций сохраняется до изменения состояния операции,
+// чтобы завершённая операция всегда имела запись в истории.
+func (w *Watcher) settle(ctx context.Context, po models.PodOperation, err error, started time.Time) Result {
+	const op = "watcher.settle"
+
+	log := w.log.With(sl.Operation(op))
+
+	attempt := po.Attempts + 1
+
+	if err == nil {
+		w.report(po, nil)
+		r := Result{Op: po, Outcome: OutcomeSucceeded}
+		w.record(ctx, r, attempt, started)
+		if err := w.s.CompleteOperation(ctx, po.ID); err != nil {
+			log.Error("failed to complete operation", sl.PodOperation(po), sl.Error(err))
+		}
+		return r
+	}
+
+	po.Attempts++
+	if po.Attempts >= w.opts.maxAttempts {
+		log.Error("operation failed permanently",
+			sl.PodOperation(po),
+			sl.Error(err),
+			slog.Int("attempts", po.Attempts),
+		)
+		r := Result{Op: po, Outcome: OutcomeDead, Err: err}
+		w.record(ctx, r, attempt, started)
+		if err := w.s.BuryOperation(ctx, po, err); err != nil {
+			log.Error("failed to bury operation", sl.PodOperation(po), sl.Error(err))
+		}
+		return r
+	}
+
+	delay := backoff(po.Attempts, w.opts.backoffBase, w.opts.backoffMax)
+	log.Warn("failed to perform operation, will retry",
+		sl.PodOperation(po),
+		sl.Error(err),
+		slog.Int("attempts", po.Attempts),
+		slog.Duration("retry_in", delay),
+	)
+	r := Result{Op: po, Outcome: OutcomeRetrying, Err: err}
+	w.record(ctx, r, attempt, started)
+	if err := w.s.RetryOperation(ctx, po, delay, err); err != nil {
+		log.Error("failed to retry operation", sl.PodOperation(po), sl.Error(err))
+	}
+	return r
+}
+
+// release возвращает невыполненные операции в очередь хранилища.
+func (w *Watcher) release(ctx context.Context, ops []models.PodOperation) []Result {
+	const op = "watcher.release"
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(ops))
+	results := make([]Result, 0, len(ops))
+	for _, po := range ops {
+		ids = append(ids, po.ID)
+		results = append(results, Result{Op: po, Outcome: OutcomeReleased})
+	}
+	if err := w.s.ReleaseOperations(ctx, ids); err != nil {
+		w.log.Error("failed to release operations", sl.Operation(op), sl.Error(err))
+	}
+	return results
+}
+
+// record сохраняет в истории операций результат выполнения операции.
+func (w *Watcher) record(ctx context.Context, r Result, attempt int, started time.Time) {
+	const op = "watcher.record"
+
+	rec := models.OperationRecord{
+		Op:         r.Op,
+		Attempt:    attempt,
+		Result:     r.Outcome,
+		StartedAt:  started,
+		FinishedAt: time.Now(),
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+
+	if err := w.s.LogOperation(ctx, rec); err != nil {
+		w.log.Error("failed to log operation", sl.Operation(op), sl.PodOperation(r.Op), sl.Error(err))
+	}
+}
+
+// report записывает в лог результат выполнения операции.
+func (w *Watcher) report(po models.PodOperation, err error) {
+	if err != nil {
+		w.log.Error("failed to perform operation", sl.PodOperation(po), sl.Error(err))
+	} else {
+		w.log.Info("operation completed", sl.PodOperation(po))
+	}
+}
+
+// perform выполняет одну операцию с подом.
+func (w *Watcher) perform(ctx context.Context, po models.PodOperation) error {
+	switch po.Code {
+	case models.OpCodeCreate:
+		return w.d.CreatePod(po.PodID)
+	case models.OpCodeDelete:
+		return w.d.DeletePod(po.PodID)
+	case models.OpCodeRestart:
+		return w.restart(ctx, po)
+	default:
+		return errUnknownOperation
+	}
+}
+
+// groupByPod разбивает операции на группы по имени пода.
+// Перезапуск surge попадает в группу пода с прежним именем.
+// Порядок групп определяется первой операцией над подом,
+// порядок операций внутри группы сохраняется.
+func groupByPod(ops []models.PodOperation) [][]models.PodOperation {
+	index := make(map[string]int)
+	groups := make([][]models.PodOperation, 0)
+
+	for _, po := range ops {
+		i, ok := index[po.PodID]
+		if !ok && po.PrevPodID != "" {
+			i, ok = index[po.PrevPodID]
+		}
+		if !ok {
+			i = len(groups)
+			groups = append(groups, nil)
+		}
+		index[po.PodID] = i
+		if po.PrevPodID != "" {
+			index[po.PrevPodID] = i
+		}
+		groups[i] = append(groups[i], po)
+	}
+
+	return groups
+}
@@ -0,0 +1,227 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/models"
+
+	"github.com/korikhin/pod-sync/pkg/deployer/mocks"
+)
+
+// fakeStorage записывает переходы операций между состояниями.
+// Методы, не используемые тестом, вызывают панику.
+type fakeStorage struct {
+	Storage
+
+	mu        sync.Mutex
+	completed []int64
+	retried   map[int64]time.Duration
+	buried    []int64
+	released  []int64
+	logged    []models.OperationRecord
+	queued    []string
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{retried: make(map[int64]time.Duration)}
+}
+
+func (s *fakeStorage) CompleteOperation(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed = append(s.completed, id)
+	return nil
+}
+
+func (s *fakeStorage) RetryOperation(_ context.Context, po models.PodOperation, delay time.Duration, _ error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retried[po.ID] = delay
+	return nil
+}
+
+func (s *fakeStorage) BuryOperation(_ context.Context, po models.PodOperation, _ error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buried = append(s.buried, po.ID)
+	return nil
+}
+
+func (s *fakeStorage) ReleaseOperations(_ context.Context, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released = append(s.released, ids...)
+	return nil
+}
+
+func (s *fakeStorage) LogOperation(_ context.Context, r models.OperationRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logged = append(s.logged, r)
+	return nil
+}
+
+func (s *fakeStorage) GetQueuedPods(_ context.Context) ([]string, error) {
+	return s.queued, nil
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestBackoff(t *testing.T) {
+	const (
+		base  = time.Second
+		limit = 10 * time.Second
+	)
+
+	tests := []struct {
+		name     string
+		attempts int
+		max      time.Duration
+	}{
+		{"no attempts", 0, limit},
+		{"first attempt", 1, base},
+		{"second attempt", 2, 2 * base},
+		{"third attempt", 3, 4 * base},
+		{"fourth attempt", 4, 8 * base},
+		{"limited", 5, limit},
+		{"overflow", 40, limit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 100 {
+				d := backoff(tt.attempts, base, limit)
+				if d < tt.max/2 || d > tt.max {
+					t.Fatalf("backoff(%d) = %v, want in [%v, %v]", tt.attempts, d, tt.max/2, tt.max)
+				}
+			}
+		})
+	}
+
+	if d := backoff(1, 0, 0); d != 0 {
+		t.Errorf("backoff with zero limit = %v, want 0", d)
+	}
+}
+
+func TestSettle(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		outcome  string
+		state    func(s *fakeStorage) bool
+	}{
+		{
+			name:    "succeeded",
+			err:     nil,
+			outcome: OutcomeSucceeded,
+			state:   func(s *fakeStorage) bool { return len(s.completed) == 1 },
+		},
+		{
+			name:     "retrying",
+			attempts: 0,
+			err:      errFailed,
+			outcome:  OutcomeRetrying,
+			state:    func(s *fakeStorage) bool { _, ok := s.retried[1]; return ok },
+		},
+		{
+			name:     "retrying before last attempt",
+			attempts: 1,
+			err:      errFailed,
+			outcome:  OutcomeRetrying,
+			state:    func(s *fakeStorage) bool { _, ok := s.retried[1]; return ok },
+		},
+		{
+			name:     "dead after last attempt",
+			attempts: 2,
+			err:      errFailed,
+			outcome:  OutcomeDead,
+			state:    func(s *fakeStorage) bool { return len(s.buried) == 1 && len(s.retried) == 0 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeStorage()
+			w := &Watcher{
+				log: discardLogger(),
+				s:   s,
+				opts: watcherOptions{
+					maxAttempts: 3,
+					backoffBase: time.Second,
+					backoffMax:  time.Minute,
+				},
+			}
+
+			po := models.PodOperation{ID: 1, PodID: "pod", Code: models.OpCodeCreate, Attempts: tt.attempts}
+			r := w.settle(context.Background(), po, tt.err, time.Now())
+
+			if r.Outcome != tt.outcome {
+				t.Fatalf("outcome = %q, want %q", r.Outcome, tt.outcome)
+			}
+			if !tt.state(s) {
+				t.Fatalf("unexpected storage state: %+v", s)
+			}
+			if len(s.logged) != 1 || s.logged[0].Attempt != tt.attempts+1 || s.logged[0].Result != tt.outcome {
+				t.Fatalf("unexpected operation log: %+v", s.logged)
+			}
+		})
+	}
+}
+
+func TestExecuteReleasesAfterRetry(t *testing.T) {
+	d := &mocks.Deployer{}
+	d.On("CreatePod", "a").Return(errors.New("failed"))
+	d.On("CreatePod", "b").Return(nil)
+	d.On("DeletePod", "a").Return(nil)
+
+	s := newFakeStorage()
+	w := &Watcher{
+		log:    discardLogger(),
+		s:      s,
+		d:      d,
+		stopCh: make(chan struct{}),
+		opts: watcherOptions{
+			workers:     2,
+			maxAttempts: 3,
+			backoffBase: time.Second,
+			backoffMax:  time.Minute,
+		},
+	}
+
+	ops := []models.PodOperation{
+		{ID: 1, PodID: "a", Code: models.OpCodeCreate},
+		{ID: 2, PodID: "b", Code: models.OpCodeCreate},
+		{ID: 3, PodID: "a", Code: models.OpCodeDelete},
+	}
+
+	outcomes := make(map[int64]string)
+	for _, r := range w.execute(context.Background(), ops, true) {
+		outcomes[r.Op.ID] = r.Outcome
+	}
+
+	want := map[int64]string{
+		1: OutcomeRetrying,
+		2: OutcomeSucceeded,
+		3: OutcomeReleased,
+	}
+	for id, outcome := range want {
+		if outcomes[id] != outcome {
+			t.Errorf("operation %d: outcome = %q, want %q", id, outcomes[id], outcome)
+		}
+	}
+	if len(s.released) != 1 || s.released[0] != 3 {
+		t.Errorf("released = %v, want [3]", s.released)
+	}
+	d.AssertNotCalled(t, "DeletePod", "a")
+}
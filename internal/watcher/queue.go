@@ -6,24 +6,87 @@ import (
 	"github.com/korikhin/pod-sync/internal/models"
 )
 
-// Данная структура реализует очередь FIFO.
+// Данная структура реализует очередь FIFO операций,
+// выбранных из хранилища и ожидающих выполнения.
+//
+// Операции над одним подом сворачиваются до минимального набора
+// с тем же результатом: удаление отменяет предшествующее создание
+// или перезапуск, повторная операция того же типа отбрасывается,
+// как и создание или перезапуск вслед за ещё не выполненным созданием
+// или перезапуском. Удаление с последующим созданием сохраняется.
 type opQueue struct {
 	ops []models.PodOperation
 	mu  sync.Mutex
+
+	// Количество операций, исключённых при сворачивании
+	collapsed uint64
 }
 
-// add добавляет операции в конец очереди.
-func (q *opQueue) add(ops []models.PodOperation) {
+// add добавляет операции в конец очереди, сворачивая операции над одним подом.
+// Возвращает операции, исключённые из очереди.
+func (q *opQueue) add(ops []models.PodOperation) []models.PodOperation {
 	q.mu.Lock()
 	defer q.mu.Unlock()
-	q.ops = append(q.ops, ops...)
+
+	collapsed := make([]models.PodOperation, 0)
+
+	for _, po := range ops {
+		i := q.last(po.PodID)
+		if i < 0 {
+			q.ops = append(q.ops, po)
+			continue
+		}
+
+		switch prev := q.ops[i]; {
+		case prev.Code == po.Code:
+			// Повторная операция не меняет результата
+			collapsed = append(collapsed, po)
+		case prev.Code == models.OpCodeCreate && po.Code == models.OpCodeDelete:
+			// Удаление отменяет ещё не выполненное создание
+			q.ops = append(q.ops[:i], q.ops[i+1:]...)
+			collapsed = append(collapsed, prev, po)
+		case prev.Code == models.OpCodeRestart && prev.PrevPodID == "" && po.Code == models.OpCodeDelete:
+			// Перезапуск пода, который будет удалён, не нужен.
+			// Перезапуск surge сохраняется: он удаляет под с прежним именем
+			q.ops = append(q.ops[:i], q.ops[i+1:]...)
+			q.ops = append(q.ops, po)
+			collapsed = append(collapsed, prev)
+		case prev.Code != models.OpCodeDelete && po.Code != models.OpCodeDelete:
+			// Под будет запущен заново предшествующей операцией
+			collapsed = append(collapsed, po)
+		default:
+			q.ops = append(q.ops, po)
+		}
+	}
+
+	q.collapsed += uint64(len(collapsed))
+	return collapsed
 }
 
-// popAll очищает очередь и возвращает все элементы.
+// last возвращает индекс последней операции над подом или -1.
+func (q *opQueue) last(podID string) int {
+	for i := len(q.ops) - 1; i >= 0; i-- {
+		if q.ops[i].PodID == podID {
+			return i
+		}
+	}
+	return -1
+}
+
+// popAll очищает очередь и возвращает все элементы
+// в порядке убывания приоритета клиента.
 func (q *opQueue) popAll() []models.PodOperation {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	ops := q.ops
 	q.ops = make([]models.PodOperation, 0, cap(ops)/2)
+	models.SortOperations(ops)
 	return ops
 }
+
+// collapsedTotal возвращает количество операций, исключённых при сворачивании.
+func (q *opQueue) collapsedTotal() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.collapsed
+}
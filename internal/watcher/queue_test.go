@@ -0,0 +1,146 @@
+package watcher
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/korikhin/pod-sync/internal/models"
+)
+
+func TestQueueAdd(t *testing.T) {
+	var (
+		create  = models.OpCodeCreate
+		del     = models.OpCodeDelete
+		restart = models.OpCodeRestart
+	)
+
+	type op struct {
+		id   int64
+		pod  string
+		code models.OpCode
+		prev string
+	}
+
+	tests := []struct {
+		name      string
+		ops       []op
+		queued    []int64
+		collapsed []int64
+	}{
+		{
+			name:   "different pods",
+			ops:    []op{{1, "a", create, ""}, {2, "b", create, ""}, {3, "c", del, ""}},
+			queued: []int64{1, 2, 3},
+		},
+		{
+			name:      "repeated create",
+			ops:       []op{{1, "a", create, ""}, {2, "a", create, ""}},
+			queued:    []int64{1},
+			collapsed: []int64{2},
+		},
+		{
+			name:      "repeated delete",
+			ops:       []op{{1, "a", del, ""}, {2, "a", del, ""}},
+			queued:    []int64{1},
+			collapsed: []int64{2},
+		},
+		{
+			name:      "create then delete",
+			ops:       []op{{1, "a", create, ""}, {2, "a", del, ""}},
+			collapsed: []int64{1, 2},
+		},
+		{
+			name:      "restart then delete",
+			ops:       []op{{1, "a", restart, ""}, {2, "a", del, ""}},
+			queued:    []int64{2},
+			collapsed: []int64{1},
+		},
+		{
+			name:   "surge restart then delete",
+			ops:    []op{{1, "a-g1", restart, "a"}, {2, "a-g1", del, ""}},
+			queued: []int64{1, 2},
+		},
+		{
+			name:      "create then restart",
+			ops:       []op{{1, "a", create, ""}, {2, "a", restart, ""}},
+			queued:    []int64{1},
+			collapsed: []int64{2},
+		},
+		{
+			name:      "restart then create",
+			ops:       []op{{1, "a", restart, ""}, {2, "a", create, ""}},
+			queued:    []int64{1},
+			collapsed: []int64{2},
+		},
+		{
+			name:   "delete then create",
+			ops:    []op{{1, "a", del, ""}, {2, "a", create, ""}},
+			queued: []int64{1, 2},
+		},
+		{
+			name:      "delete then create then delete",
+			ops:       []op{{1, "a", del, ""}, {2, "a", create, ""}, {3, "a", del, ""}},
+			queued:    []int64{1},
+			collapsed: []int64{2, 3},
+		},
+		{
+			name:      "interleaved pods",
+			ops:       []op{{1, "a", create, ""}, {2, "b", create, ""}, {3, "a", del, ""}, {4, "b", create, ""}},
+			queued:    []int64{2},
+			collapsed: []int64{1, 3, 4},
+		},
+	}
+
+	ids := func(ops []models.PodOperation) []int64 {
+		ids := make([]int64, 0, len(ops))
+		for _, po := range ops {
+			ids = append(ids, po.ID)
+		}
+		return ids
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := make([]models.PodOperation, 0, len(tt.ops))
+			for _, o := range tt.ops {
+				ops = append(ops, models.PodOperation{ID: o.id, PodID: o.pod, Code: o.code, PrevPodID: o.prev})
+			}
+
+			q := &opQueue{}
+			collapsed := q.add(ops)
+
+			if got := ids(q.ops); !slices.Equal(got, tt.queued) {
+				t.Errorf("queued = %v, want %v", got, tt.queued)
+			}
+			got := ids(collapsed)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.collapsed) {
+				t.Errorf("collapsed = %v, want %v", got, tt.collapsed)
+			}
+			if q.collapsedTotal() != uint64(len(tt.collapsed)) {
+				t.Errorf("collapsed total = %d, want %d", q.collapsedTotal(), len(tt.collapsed))
+			}
+		})
+	}
+}
+
+func TestQueuePopAll(t *testing.T) {
+	q := &opQueue{}
+	q.add([]models.PodOperation{
+		{ID: 1, PodID: "a", Code: models.OpCodeCreate, Priority: 1},
+		{ID: 2, PodID: "b", Code: models.OpCodeCreate, Priority: 5},
+		{ID: 3, PodID: "c", Code: models.OpCodeCreate, Priority: 1},
+		{ID: 4, PodID: "d", Code: models.OpCodeDelete, Priority: 5},
+	})
+
+	got := make([]int64, 0, 4)
+	for _, po := range q.popAll() {
+		got = append(got, po.ID)
+	}
+	if want := []int64{2, 4, 1, 3}; !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	if len(q.popAll()) != 0 {
+		t.Error("queue is not empty after popAll")
+	}
+}
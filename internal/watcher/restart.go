@@ -0,0 +1,195 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/models"
+)
+
+var (
+	errNotReady = errors.New("pod is not ready")
+
+	// Операция не начата и может быть возвращена в очередь без попытки
+	errNotStarted = errors.New("operation not started")
+)
+
+type restartOptions struct {
+	rolling           bool
+	readyTimeout      time.Duration
+	readyPollInterval time.Duration
+}
+
+// restart перезапускает под: удаляет и создаёт его заново.
+// Повторная попытка не удаляет под, отсутствующий после неудачного создания.
+//
+// При последовательном перезапуске количество одновременно недоступных
+// подов ограничено бюджетом, а место в бюджете освобождается только после
+// появления пересозданного пода в списке запущенных подов.
+//
+// Перезапуск surge выполняется без удаления пода до запуска нового, см. surge.
+func (w *Watcher) restart(ctx context.Context, po models.PodOperation) error {
+	if po.PrevPodID != "" {
+		return w.surge(ctx, po)
+	}
+
+	if !w.opts.restart.rolling {
+		return w.recreate(po.PodID)
+	}
+
+	release, err := w.budget.acquire(ctx, po.ClientID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errNotStarted, err)
+	}
+	defer release()
+
+	if err := w.recreate(po.PodID); err != nil {
+		return err
+	}
+	return w.waitReady(ctx, po.PodID)
+}
+
+// recreate удаляет под, если он запущен, и создаёт его заново.
+func (w *Watcher) recreate(podID string) error {
+	pods, err := w.d.GetPodList()
+	if err != nil {
+		return fmt.Errorf("%w: %w", errNotStarted, err)
+	}
+
+	if slices.Contains(pods, podID) {
+		if err := w.d.DeletePod(podID); err != nil {
+			return err
+		}
+	}
+	return w.d.CreatePod(podID)
+}
+
+// surge перезапускает под без простоя: создаёт под нового поколения,
+// дожидается его появления в списке запущенных подов и только затем
+// удаляет под с прежним именем.
+//
+// Бюджет недоступности не расходуется, поскольку прежний под работает
+// до запуска нового. Повторная попытка не создаёт под нового поколения,
+// если он уже запущен, и не удаляет отсутствующий прежний под.
+func (w *Watcher) surge(ctx context.Context, po models.PodOperation) error {
+	pods, err := w.d.GetPodList()
+	if err != nil {
+		return fmt.Errorf("%w: %w", errNotStarted, err)
+	}
+
+	if !slices.Contains(pods, po.PodID) {
+		if err := w.d.CreatePod(po.PodID); err != nil {
+			return err
+		}
+	}
+	if err := w.waitReady(ctx, po.PodID); err != nil {
+		return err
+	}
+
+	if !slices.Contains(pods, po.PrevPodID) {
+		return nil
+	}
+	return w.d.DeletePod(po.PrevPodID)
+}
+
+// waitReady ожидает появления пода в списке запущенных подов.
+func (w *Watcher) waitReady(ctx context.Context, podID string) error {
+	timeout := time.NewTimer(w.opts.restart.readyTimeout)
+	defer timeout.Stop()
+
+	ticker := time.NewTicker(w.opts.restart.readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		pods, err := w.d.GetPodList()
+		if err == nil && slices.Contains(pods, podID) {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			return fmt.Errorf("%w: %s", errNotReady, podID)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// restartBudget ограничивает количество подов, одновременно недоступных
+// из-за перезапуска, в целом и для каждого клиента.
+type restartBudget struct {
+	global    chan struct{} // nil — без ограничений
+	perClient int           // 0 — без ограничений
+
+	mu      sync.Mutex
+	clients map[int]chan struct{}
+}
+
+func newRestartBudget(global, perClient int) *restartBudget {
+	b := &restartBudget{
+		perClient: perClient,
+		clients:   make(map[int]chan struct{}),
+	}
+	if global > 0 {
+		b.global = make(chan struct{}, global)
+	}
+	return b
+}
+
+// acquire занимает место в бюджете клиента и в общем бюджете.
+// Возвращает функцию освобождения места или ошибку контекста.
+func (b *restartBudget) acquire(ctx context.Context, clientID int) (func(), error) {
+	client := b.client(clientID)
+
+	// Место клиента занимается первым, чтобы не удерживать общее место,
+	// ожидая перезапуска других подов того же клиента
+	if client != nil {
+		select {
+		case client <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if b.global != nil {
+		select {
+		case b.global <- struct{}{}:
+		case <-ctx.Done():
+			if client != nil {
+				<-client
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		if b.global != nil {
+			<-b.global
+		}
+		if client != nil {
+			<-client
+		}
+	}
+	return release, nil
+}
+
+// client возвращает бюджет клиента или nil, если он не ограничен.
+func (b *restartBudget) client(clientID int) chan struct{} {
+	if b.perClient <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch, ok := b.clients[clientID]
+	if !ok {
+		ch = make(chan struct{}, b.perClient)
+		b.clients[clientID] = ch
+	}
+	return ch
+}
@@ -0,0 +1,69 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/models"
+
+	"github.com/korikhin/pod-sync/pkg/deployer/mocks"
+)
+
+func TestRestart(t *testing.T) {
+	errList := errors.New("list failed")
+
+	tests := []struct {
+		name    string
+		pods    []string
+		listErr error
+		deleted bool
+		created bool
+		wantErr error
+	}{
+		{"running pod", []string{"X-1", "Y-1"}, nil, true, true, nil},
+		{"missing pod after failed create", []string{"Y-1"}, nil, false, true, nil},
+		{"pod list unavailable", nil, errList, false, false, errNotStarted},
+	}
+
+	for _, tt := range tests {
+		for _, rolling := range []bool{false, true} {
+			d := &mocks.Deployer{}
+			d.On("GetPodList").Return(tt.pods, tt.listErr).Once()
+			// Пересозданный под появляется в списке запущенных подов
+			d.On("GetPodList").Return([]string{"X-1"}, nil)
+			d.On("DeletePod", "X-1").Return(nil)
+			d.On("CreatePod", "X-1").Return(nil)
+
+			w := &Watcher{
+				d:      d,
+				budget: newRestartBudget(1, 1),
+				opts: watcherOptions{
+					restart: restartOptions{
+						rolling:           rolling,
+						readyTimeout:      time.Second,
+						readyPollInterval: time.Millisecond,
+					},
+				},
+			}
+
+			po := models.PodOperation{ID: 1, PodID: "X-1", Code: models.OpCodeRestart, ClientID: 1}
+			err := w.restart(context.Background(), po)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%s (rolling %v): error = %v, want %v", tt.name, rolling, err, tt.wantErr)
+			}
+			if tt.deleted {
+				d.AssertCalled(t, "DeletePod", "X-1")
+			} else {
+				d.AssertNotCalled(t, "DeletePod", "X-1")
+			}
+			if tt.created {
+				d.AssertCalled(t, "CreatePod", "X-1")
+			} else {
+				d.AssertNotCalled(t, "CreatePod", "X-1")
+			}
+		}
+	}
+}
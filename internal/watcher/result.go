@@ -0,0 +1,24 @@
+package watcher
+
+import "github.com/korikhin/pod-sync/internal/models"
+
+// Исходы выполнения операций
+const (
+	// Операция выполнена
+	OutcomeSucceeded = "succeeded"
+	// Операция не выполнена и будет повторена
+	OutcomeRetrying = "retrying"
+	// Операция не выполнена за максимальное количество попыток
+	OutcomeDead = "dead"
+	// Операция сверки не выполнена, расхождение будет устранено следующей сверкой
+	OutcomeFailed = "failed"
+	// Операция не начата и возвращена в очередь
+	OutcomeReleased = "released"
+)
+
+// Result описывает исход выполнения операции с подом.
+type Result struct {
+	Op      models.PodOperation
+	Outcome string
+	Err     error
+}
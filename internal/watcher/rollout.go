@@ -0,0 +1,77 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+)
+
+// advanceRollout выполняет очередной шаг выполняющегося выката:
+// останавливает его при превышении доли неудачных операций волны,
+// начинает паузу или следующую волну либо завершает выкат.
+// Операции начатой волны выполняются сразу.
+//
+// Вызывается только из основного цикла Watcher'а.
+func (w *Watcher) advanceRollout() {
+	const op = "watcher.advanceRollout"
+
+	log := w.log.With(sl.Operation(op))
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.syncInterval)
+	defer cancel()
+
+	r, err := w.s.GetRunningRollout(ctx)
+	if err != nil {
+		log.Error("failed to get rollout", sl.Error(err))
+		return
+	}
+	if r == nil {
+		return
+	}
+
+	log = log.With(
+		slog.Int64("rollout_id", r.ID),
+		slog.Int("wave", r.Wave),
+	)
+
+	now := time.Now()
+	switch r.Step(now) {
+	case models.RolloutHalt:
+		reason := r.HaltReason()
+		if err := w.s.FinishRollout(ctx, r.ID, models.RolloutHalted, reason); err != nil {
+			log.Error("failed to halt rollout", sl.Error(err))
+			return
+		}
+		log.Warn("rollout halted", slog.String("reason", reason))
+	case models.RolloutComplete:
+		if err := w.s.FinishRollout(ctx, r.ID, models.RolloutCompleted, ""); err != nil {
+			log.Error("failed to complete rollout", sl.Error(err))
+			return
+		}
+		log.Info("rollout completed", slog.Int("clients", r.Clients))
+	case models.RolloutPause:
+		if err := w.s.SetRolloutNextWave(ctx, r.ID, now.Add(r.Pause)); err != nil {
+			log.Error("failed to pause rollout", sl.Error(err))
+			return
+		}
+		log.Info("rollout wave finished", slog.Duration("pause", r.Pause))
+	case models.RolloutNextWave:
+		ops, err := w.s.StartRolloutWave(ctx, *r)
+		if err != nil {
+			log.Error("failed to start rollout wave", sl.Error(err))
+			return
+		}
+		log.Info("rollout wave started", slog.Int("next_wave", r.Wave+1), slog.Int("operations", len(ops)))
+
+		if len(ops) == 0 {
+			return
+		}
+		if err := w.claim(ctx, 0); err != nil {
+			return
+		}
+		w.execute(ctx, w.queue.popAll(), true)
+	}
+}
@@ -0,0 +1,71 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+	"github.com/korikhin/pod-sync/internal/models"
+)
+
+// applySchedules применяет расписания, для которых наступил момент смены
+// состояния подов. Изменения статусов и операции регистрируются в хранилище
+// так же, как при изменении через API. Если execute установлен,
+// зарегистрированные операции выполняются сразу.
+//
+// Расписание, для включения подов которого не хватает ресурсов кластера,
+// не применяется и проверяется повторно при следующем вызове.
+//
+// Вызывается только из основного цикла Watcher'а.
+func (w *Watcher) applySchedules(execute bool) {
+	const op = "watcher.applySchedules"
+
+	log := w.log.With(sl.Operation(op))
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.syncInterval)
+	defer cancel()
+
+	schedules, err := w.s.GetPendingSchedules(ctx)
+	if err != nil {
+		log.Error("failed to get schedules", sl.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for _, sc := range schedules {
+		log := log.With(
+			slog.Int("schedule_id", sc.ID),
+			slog.Int("status_id", sc.StatusID),
+		)
+
+		on, due, err := sc.Due(now)
+		if err != nil {
+			log.Error("failed to evaluate schedule", sl.Error(err))
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		ops, err := w.s.ApplySchedule(ctx, sc, on)
+		if err != nil {
+			if errors.Is(err, models.ErrCapacityExceeded) {
+				log.Warn("schedule postponed", sl.Error(err))
+				continue
+			}
+			log.Error("failed to apply schedule", sl.Error(err))
+			continue
+		}
+		log.Info("schedule applied", slog.Bool("on", on), slog.Int("operations", len(ops)))
+
+		if !execute || len(ops) == 0 {
+			continue
+		}
+		if err := w.claim(ctx, sc.StatusID); err != nil {
+			continue
+		}
+		w.execute(ctx, w.queue.popAll(), true)
+	}
+}
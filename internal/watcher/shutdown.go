@@ -0,0 +1,87 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+)
+
+// Политики остановки
+const (
+	// Выполнять операции из очереди до истечения контекста остановки
+	ShutdownDrain = "drain"
+	// Прекратить выполнение операций, оставив их в очереди
+	ShutdownRelease = "release"
+)
+
+// Stop отправляет сигнал об остановке и ожидает ответного сигнала об остановке.
+//
+// В зависимости от политики остановки Watcher выполняет оставшиеся операции
+// до истечения ctx либо прекращает их выполнение. Невыполненные операции
+// возвращаются в очередь хранилища и будут выполнены после запуска.
+// Возвращает ошибку контекста, если Watcher не остановился до истечения ctx.
+// В этом случае лидерство и подписка на уведомления прекращаются,
+// чтобы их соединения не задерживали остановку хранилища.
+func (w *Watcher) Stop(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+
+	w.stopCtx = ctx
+	close(w.stopCh)
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
+
+// halted сообщает, следует ли прекратить выполнение операций.
+func (w *Watcher) halted() bool {
+	select {
+	case <-w.stopCh:
+		if w.opts.shutdownPolicy == ShutdownRelease {
+			return true
+		}
+		return w.stopCtx.Err() != nil
+	default:
+		return false
+	}
+}
+
+// drain выполняет операции из очереди, пока очередь не опустеет
+// или не истечёт контекст остановки.
+func (w *Watcher) drain() {
+	const op = "watcher.drain"
+
+	log := w.log.With(sl.Operation(op))
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.syncInterval)
+	defer cancel()
+
+	if w.opts.shutdownPolicy == ShutdownDrain {
+		for !w.halted() {
+			if err := w.claim(ctx, 0); err != nil {
+				break
+			}
+			ops := w.queue.popAll()
+			if len(ops) == 0 {
+				break
+			}
+			w.execute(ctx, ops, true)
+		}
+	}
+
+	n, err := w.s.CountQueuedOperations(ctx)
+	if err != nil {
+		log.Error("failed to count queued operations", sl.Error(err))
+		return
+	}
+	if n > 0 {
+		log.Warn("operations left in queue", slog.Int("count", n))
+	}
+}
@@ -0,0 +1,87 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNotLeader = errors.New("watcher is not the leader")
+
+type syncRequest struct {
+	clientID int
+	resp     chan syncResponse
+}
+
+type syncResponse struct {
+	results []Result
+	err     error
+}
+
+// Sync немедленно выполняет зарегистрированные операции и сверку,
+// не дожидаясь очередного интервала. Если clientID не равен нулю,
+// синхронизируются только поды клиента.
+//
+// Синхронизацию выполняет лидер, поэтому на остальных экземплярах сервиса
+// возвращается ErrNotLeader. ErrNotLeader возвращается и при потере лидерства
+// или остановке Watcher'а до начала синхронизации.
+// Возвращает исходы выполненных операций.
+func (w *Watcher) Sync(ctx context.Context, clientID int) ([]Result, error) {
+	if !w.IsLeader() {
+		return nil, ErrNotLeader
+	}
+	// Канал сохраняется до установки признака лидерства,
+	// поэтому относится к текущему или более позднему лидерству
+	resigned := *w.resigned.Load()
+
+	req := syncRequest{
+		clientID: clientID,
+		resp:     make(chan syncResponse, 1),
+	}
+
+	select {
+	case w.syncCh <- req:
+	case <-resigned:
+		return nil, ErrNotLeader
+	case <-w.stopCh:
+		return nil, ErrNotLeader
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case resp := <-req.resp:
+		return resp.results, resp.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// serve выполняет запрос на немедленную синхронизацию.
+func (w *Watcher) serve(req syncRequest) {
+	var resp syncResponse
+	if req.clientID == 0 {
+		resp.results, resp.err = w.sync()
+	} else {
+		resp.results, resp.err = w.syncClient(req.clientID)
+	}
+	req.resp <- resp
+}
+
+// syncClient выполняет зарегистрированные операции и сверку подов клиента.
+func (w *Watcher) syncClient(clientID int) ([]Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.syncInterval)
+	defer cancel()
+
+	status, err := w.s.GetClientStatus(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.claim(ctx, status.ID); err != nil {
+		return nil, err
+	}
+	results := w.execute(ctx, w.queue.popAll(), true)
+
+	rs, err := w.reconcileStatus(ctx, status.ID)
+	return append(results, rs...), err
+}
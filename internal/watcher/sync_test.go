@@ -0,0 +1,46 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSyncNotServed(t *testing.T) {
+	tests := []struct {
+		name   string
+		resign bool
+		stop   bool
+	}{
+		{"leadership lost", true, false},
+		{"watcher stopped", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Лидерство получено, но основной цикл не принимает запросы
+			w := &Watcher{
+				syncCh: make(chan syncRequest),
+				stopCh: make(chan struct{}),
+			}
+			resigned := make(chan struct{})
+			w.resigned.Store(&resigned)
+			w.leader.Store(true)
+
+			if tt.resign {
+				close(resigned)
+			}
+			if tt.stop {
+				close(w.stopCh)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			if _, err := w.Sync(ctx, 0); !errors.Is(err, ErrNotLeader) {
+				t.Fatalf("Sync() error = %v, want %v", err, ErrNotLeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/korikhin/pod-sync/internal/lib/logger/sl"
+)
+
+// expirePods выключает поды, время жизни которых истекло, и регистрирует
+// операции их удаления. Если execute установлен, операции выполняются сразу.
+//
+// Вызывается только из основного цикла Watcher'а.
+func (w *Watcher) expirePods(execute bool) {
+	const op = "watcher.expirePods"
+
+	log := w.log.With(sl.Operation(op))
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.syncInterval)
+	defer cancel()
+
+	ops, err := w.s.ExpirePods(ctx, w.opts.ttl)
+	if err != nil {
+		log.Error("failed to expire pods", sl.Error(err))
+		return
+	}
+	if len(ops) == 0 {
+		return
+	}
+
+	for _, po := range ops {
+		log.Info("pod expired", slog.String("pod_id", po.PodID), slog.Int("status_id", po.StatusID))
+	}
+
+	if !execute {
+		return
+	}
+	if err := w.claim(ctx, 0); err != nil {
+		return
+	}
+	w.execute(ctx, w.queue.popAll(), true)
+}
@@ -1,7 +1,10 @@
 package watcher
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/korikhin/pod-sync/internal/config"
@@ -11,54 +14,217 @@ import (
 	"github.com/korikhin/pod-sync/pkg/deployer"
 )
 
+type Storage interface {
+	// GetStatuses возвращает все статусы.
+	GetStatuses(ctx context.Context) ([]models.Status, error)
+
+	// GetStatus возвращает статус.
+	GetStatus(ctx context.Context, id int) (*models.Status, error)
+
+	// ListenStatus подписывается на уведомления об изменении статусов
+	// и вызывает fn с идентификатором изменённого статуса.
+	// Блокируется до отмены ctx или разрыва соединения.
+	ListenStatus(ctx context.Context, fn func(id int)) error
+
+	// GetClientStatus возвращает статус клиента.
+	GetClientStatus(ctx context.Context, clientID int) (*models.Status, error)
+
+	// ClaimOperations переводит в состояние выполнения не более limit операций,
+	// готовых к выполнению, и возвращает их в порядке убывания приоритета клиента.
+	// Если statusID не равен нулю, выбираются только операции над подами статуса.
+	ClaimOperations(ctx context.Context, limit int, statusID int) ([]models.PodOperation, error)
+
+	// CompleteOperation удаляет успешно выполненную операцию.
+	CompleteOperation(ctx context.Context, id int64) error
+
+	// CancelOperations удаляет операции, исключённые из очереди без выполнения,
+	// и сохраняет их в истории операций как отменённые.
+	CancelOperations(ctx context.Context, ids []int64) error
+
+	// RetryOperation возвращает неудачную операцию в очередь.
+	// Операция будет выбрана для выполнения не ранее, чем через delay.
+	RetryOperation(ctx context.Context, po models.PodOperation, delay time.Duration, cause error) error
+
+	// BuryOperation переносит операцию, исчерпавшую попытки, в список dead letters.
+	BuryOperation(ctx context.Context, po models.PodOperation, cause error) error
+
+	// ReleaseOperations возвращает выбранные, но не выполненные операции в очередь.
+	ReleaseOperations(ctx context.Context, ids []int64) error
+
+	// ReleaseStaleOperations возвращает в очередь операции, выбранные для выполнения
+	// ранее, чем timeout назад. Возвращает количество возвращённых операций.
+	ReleaseStaleOperations(ctx context.Context, timeout time.Duration) (int64, error)
+
+	// CountQueuedOperations возвращает количество невыполненных операций.
+	CountQueuedOperations(ctx context.Context) (int, error)
+
+	// GetQueuedPods возвращает имена подов, для которых есть невыполненные операции.
+	GetQueuedPods(ctx context.Context) ([]string, error)
+
+	// LogOperation сохраняет запись о выполнении операции с подом.
+	LogOperation(ctx context.Context, r models.OperationRecord) error
+
+	// IsPaused сообщает, приостановлено ли выполнение операций.
+	IsPaused(ctx context.Context) (bool, error)
+
+	// GetPendingSchedules возвращает расписания, которые могут потребовать
+	// применения: не применённые разовые и все повторяющиеся.
+	GetPendingSchedules(ctx context.Context) ([]models.Schedule, error)
+
+	// ApplySchedule включает или выключает поды расписания и отмечает его применённым.
+	// Возвращает зарегистрированные операции с подами.
+	ApplySchedule(ctx context.Context, sc models.Schedule, on bool) ([]models.PodOperation, error)
+
+	// ExpirePods выключает поды, время жизни которых истекло.
+	// Возвращает зарегистрированные операции удаления подов.
+	ExpirePods(ctx context.Context, ttl models.PodTTL) ([]models.PodOperation, error)
+
+	// GetRunningRollout возвращает выполняющийся выкат или nil, если его нет.
+	GetRunningRollout(ctx context.Context) (*models.Rollout, error)
+
+	// StartRolloutWave начинает следующую волну выката.
+	// Возвращает зарегистрированные операции перезапуска подов.
+	StartRolloutWave(ctx context.Context, r models.Rollout) ([]models.PodOperation, error)
+
+	// SetRolloutNextWave задаёт время начала следующей волны выката.
+	SetRolloutNextWave(ctx context.Context, id int64, at time.Time) error
+
+	// FinishRollout завершает или останавливает выполняющийся выкат.
+	FinishRollout(ctx context.Context, id int64, state, reason string) error
+
+	// SetPaused приостанавливает или возобновляет выполнение операций.
+	SetPaused(ctx context.Context, paused bool) error
+
+	// TryLead пытается получить лидерство.
+	// Возвращает nil, если лидером является другой экземпляр сервиса.
+	TryLead(ctx context.Context) (Leadership, error)
+}
+
 type watcherOptions struct {
 	syncInterval time.Duration
+	workers      int
+	batchSize    int
+	maxAttempts  int
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+
+	shutdownPolicy   string
+	electionInterval time.Duration
+	scheduleInterval time.Duration
+	claimTimeout     time.Duration
+
+	gc      gcOptions
+	restart restartOptions
+	ttl     models.PodTTL
 }
 
 type Watcher struct {
 	log   *slog.Logger
+	s     Storage
 	d     deployer.Deployer
+	namer *models.PodNamer
 	queue *opQueue
 	opts  watcherOptions
 
+	// Бюджет недоступности подов при последовательном перезапуске
+	budget *restartBudget
+
+	// Признак лидерства
+	leader atomic.Bool
+
+	// Канал, закрываемый при потере лидерства, полученного последним
+	resigned atomic.Pointer[chan struct{}]
+
+	// Последнее известное состояние приостановки
+	paused atomic.Bool
+
+	// Канал запросов на немедленную синхронизацию
+	syncCh chan syncRequest
+
+	// Бесхозные поды и время их обнаружения
+	orphans map[string]time.Time
+
 	// Канал для отправки команды на завершение
 	stopCh chan struct{}
 
+	// Контекст остановки, устанавливается до закрытия stopCh
+	stopCtx context.Context
+
+	// Контекст работы Watcher'а, отменяется, если Watcher
+	// не остановился до истечения контекста остановки
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	// Канал для отправки сигнала об успешном завершении
 	done chan struct{}
 }
 
-func New(log *slog.Logger, d deployer.Deployer, cfg config.Sync) *Watcher {
+func New(log *slog.Logger, s Storage, d deployer.Deployer, n *models.PodNamer, cfg config.Sync) *Watcher {
 	log = log.With(sl.Component("sync/watcher"))
 
+	shutdownPolicy := cfg.ShutdownPolicy
+	if shutdownPolicy != ShutdownDrain && shutdownPolicy != ShutdownRelease {
+		log.Warn("unknown shutdown policy, using default", slog.String("policy", shutdownPolicy))
+		shutdownPolicy = ShutdownDrain
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Watcher{
-		log:    log,
-		d:      d,
-		queue:  &opQueue{},
-		opts:   watcherOptions{syncInterval: cfg.Interval},
+		log:   log,
+		s:     s,
+		d:     d,
+		namer: n,
+		queue: &opQueue{},
+		opts: watcherOptions{
+			syncInterval: cfg.Interval,
+			workers:      max(cfg.Workers, 1),
+			batchSize:    max(cfg.BatchSize, 1),
+			maxAttempts:  max(cfg.MaxAttempts, 1),
+			backoffBase:  cfg.BackoffBase,
+			backoffMax:   cfg.BackoffMax,
+
+			shutdownPolicy:   shutdownPolicy,
+			electionInterval: cfg.ElectionInterval,
+			scheduleInterval: cfg.ScheduleInterval,
+			claimTimeout:     cfg.ClaimTimeout,
+
+			gc: gcOptions{
+				enabled:      cfg.GC.Enabled,
+				gracePeriod:  cfg.GC.GracePeriod,
+				maxDeletions: cfg.GC.MaxDeletions,
+				allowlist:    cfg.GC.Allowlist,
+			},
+			restart: restartOptions{
+				rolling:           cfg.Restart.Rolling,
+				readyTimeout:      cfg.Restart.ReadyTimeout,
+				readyPollInterval: cfg.Restart.ReadyPollInterval,
+			},
+			ttl: models.PodTTL{
+				X: cfg.TTL.X,
+				Y: cfg.TTL.Y,
+				Z: cfg.TTL.Z,
+			},
+		},
+		budget: newRestartBudget(cfg.Restart.MaxUnavailable, cfg.Restart.MaxUnavailablePerClient),
+		syncCh: make(chan syncRequest),
 		stopCh: make(chan struct{}),
 		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
-// QueueOperations заносит операции в очередь на выполнение.
-func (w *Watcher) QueueOperations(ops []models.PodOperation) {
-	if len(ops) == 0 {
-		return
-	}
-	w.queue.add(ops)
-	return
+// Stats содержит счётчики Watcher'а.
+type Stats struct {
+	OperationsCollapsed uint64
 }
 
-// Stop отправляет сигнал об остановке и ожидает ответного сигнала об остановке.
-//
-// TODO: Добавить context.Context остановки.
-func (w *Watcher) Stop() {
-	if w == nil {
-		return
+// Stats возвращает текущие значения счётчиков.
+func (w *Watcher) Stats() Stats {
+	return Stats{
+		OperationsCollapsed: w.queue.collapsedTotal(),
 	}
-	close(w.stopCh)
-	<-w.done
 }
 
 func (w *Watcher) Start() {
@@ -68,38 +234,188 @@ func (w *Watcher) Start() {
 	go w.start()
 }
 
-// start запускает основной цикл Watcher'а по обработке операций из очереди.
-// Обрабатывает операции с подами по таймеру и завершается при вызове Stop.
-//
-// TODO: Предусмотреть механизм параллельного выполнения операций.
+// start запускает основной цикл Watcher'а.
+// Экземпляр сервиса периодически пытается получить лидерство; лидер по таймеру
+// выполняет операции из хранилища, после чего сверяет запущенные поды
+// со статусами. При потере лидерства синхронизация прекращается до его
+// повторного получения. Завершается при вызове Stop согласно политике остановки.
 func (w *Watcher) start() {
 	defer close(w.done)
-	ticker := time.NewTicker(w.opts.syncInterval)
+	ticker := time.NewTicker(w.opts.electionInterval)
 	defer ticker.Stop()
 
 	for {
+		if l := w.elect(); l != nil {
+			if stopped := w.lead(l); stopped {
+				return
+			}
+		}
+
 		select {
 		case <-ticker.C:
-			for _, po := range w.queue.popAll() {
-				switch po.Code {
-				case models.OpCodeCreate:
-					if err := w.d.CreatePod(po.PodID); err != nil {
-						w.log.Error("failed to perform operation", sl.PodOperation(po), sl.Error(err))
-					} else {
-						w.log.Info("operation completed", sl.PodOperation(po))
-					}
-				case models.OpCodeDelete:
-					if err := w.d.DeletePod(po.PodID); err != nil {
-						w.log.Error("failed to perform operation", sl.PodOperation(po), sl.Error(err))
-					} else {
-						w.log.Info("operation completed", sl.PodOperation(po))
-					}
-				default:
-					w.log.Warn("unknown operation", sl.PodOperation(po))
-				}
-			}
 		case <-w.stopCh:
 			return
 		}
 	}
 }
+
+// sync выполняет зарегистрированные операции и сверку.
+func (w *Watcher) sync() ([]Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.syncInterval)
+	defer cancel()
+
+	if err := w.claim(ctx, 0); err != nil {
+		return nil, err
+	}
+	results := w.execute(ctx, w.queue.popAll(), true)
+
+	rs, err := w.reconcile(ctx)
+	return append(results, rs...), err
+}
+
+// restore возвращает в очередь операции, оставшиеся в состоянии выполнения
+// дольше opts.claimTimeout. Операции, выбранные позднее, могут ещё выполняться
+// прежним лидером, поэтому не возвращаются.
+func (w *Watcher) restore() {
+	const op = "watcher.restore"
+
+	log := w.log.With(sl.Operation(op))
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.syncInterval)
+	defer cancel()
+
+	n, err := w.s.ReleaseStaleOperations(ctx, w.opts.claimTimeout)
+	if err != nil {
+		log.Error("failed to release stale operations", sl.Error(err))
+		return
+	}
+	if n > 0 {
+		log.Warn("stale operations released", slog.Int64("count", n))
+	}
+}
+
+// claim выбирает из хранилища операции, готовые к выполнению, и заносит их в очередь.
+// Если statusID не равен нулю, выбираются только операции над подами статуса.
+func (w *Watcher) claim(ctx context.Context, statusID int) error {
+	const op = "watcher.claim"
+
+	log := w.log.With(sl.Operation(op))
+
+	ops, err := w.s.ClaimOperations(ctx, w.opts.batchSize, statusID)
+	if err != nil {
+		log.Error("failed to claim operations", sl.Error(err))
+		return err
+	}
+
+	collapsed := w.queue.add(ops)
+	if len(collapsed) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(collapsed))
+	for _, po := range collapsed {
+		ids = append(ids, po.ID)
+	}
+	if err := w.s.CancelOperations(ctx, ids); err != nil {
+		// Операции будут выбраны и свёрнуты повторно
+		log.Error("failed to cancel collapsed operations", sl.Error(err))
+		w.release(ctx, collapsed)
+		return nil
+	}
+	log.Info("operations collapsed", slog.Int("count", len(collapsed)))
+	return nil
+}
+
+// reconcile сверяет запущенные поды со статусами в хранилище
+// и выполняет операции, необходимые для их соответствия.
+//
+// Сверка дополняет очередь операций: изменения, поступившие через API,
+// выполняются из очереди, а сверка устраняет расхождения, возникшие
+// вне сервиса (потерянные операции, поды, удалённые извне).
+func (w *Watcher) reconcile(ctx context.Context) ([]Result, error) {
+	const op = "watcher.reconcile"
+
+	statuses, err := w.s.GetStatuses(ctx)
+	if err != nil {
+		w.log.Error("failed to get statuses", sl.Operation(op), sl.Error(err))
+		return nil, err
+	}
+
+	return w.reconcileStatuses(ctx, statuses, w.opts.gc.enabled)
+}
+
+// reconcileStatuses сверяет поды заданных статусов.
+// Если collect установлен, также удаляются поды, оставшиеся от удалённых
+// клиентов, поэтому statuses должны содержать все статусы.
+func (w *Watcher) reconcileStatuses(ctx context.Context, statuses []models.Status, collect bool) ([]Result, error) {
+	const op = "watcher.reconcileStatuses"
+
+	log := w.log.With(sl.Operation(op))
+
+	// Поды, для которых в очереди есть операции (в том числе ожидающие
+	// повторной попытки), не затрагиваются, чтобы не нарушить их порядок
+	queuedPods, err := w.s.GetQueuedPods(ctx)
+	if err != nil {
+		log.Error("failed to get queued pods", sl.Error(err))
+		return nil, err
+	}
+
+	pods, err := w.d.GetPodList()
+	if err != nil {
+		if errors.Is(err, deployer.ErrUnavailable) {
+			log.Warn("deployer is unavailable, reconciliation skipped")
+			return nil, err
+		}
+		log.Error("failed to get pod list", sl.Error(err))
+		return nil, err
+	}
+
+	queued := make(map[string]struct{}, len(queuedPods))
+	for _, p := range queuedPods {
+		queued[p] = struct{}{}
+	}
+
+	ops := make([]models.PodOperation, 0)
+	for _, po := range models.SyncOperations(w.namer, statuses, pods) {
+		if _, ok := queued[po.PodID]; !ok {
+			ops = append(ops, po)
+		}
+	}
+
+	if collect {
+		// Поды с устаревшими именами могут не принадлежать сервису,
+		// поэтому удаляются только вместе с бесхозными подами
+		d := models.DetectDrift(w.namer, statuses, pods)
+		candidates := d.Orphans
+		for _, p := range d.Stale {
+			candidates = append(candidates, p.ID)
+		}
+		orphans := w.collectOrphans(candidates, queued)
+		if len(orphans) > 0 {
+			log.Info("deleting orphan pods", slog.Int("count", len(orphans)))
+			ops = append(ops, orphans...)
+		}
+	}
+
+	if len(ops) == 0 {
+		return nil, nil
+	}
+
+	log.Info("pods are out of sync", slog.Int("operations", len(ops)))
+	return w.execute(ctx, ops, false), nil
+}
+
+// Drift сравнивает статусы в хранилище с запущенными подами, не изменяя их.
+func (w *Watcher) Drift(ctx context.Context) (models.Drift, error) {
+	statuses, err := w.s.GetStatuses(ctx)
+	if err != nil {
+		return models.Drift{}, err
+	}
+
+	pods, err := w.d.GetPodList()
+	if err != nil {
+		return models.Drift{}, err
+	}
+
+	return models.DetectDrift(w.namer, statuses, pods), nil
+}
@@ -0,0 +1,17 @@
+create table if not exists watcher.operations (
+    id bigserial primary key,
+    pod_id varchar(100) not null,
+    code smallint not null,
+    state varchar(10) not null default 'pending',
+    attempts integer not null default 0,
+    not_before timestamp not null default timezone('UTC', now()),
+    error text,
+    created_at timestamp not null default timezone('UTC', now()),
+    updated_at timestamp not null default timezone('UTC', now()),
+
+    check (state in ('pending', 'running', 'dead'))
+);
+
+create index if not exists operations_pending_idx
+    on watcher.operations (pod_id, id)
+    where state in ('pending', 'running');
@@ -0,0 +1,2 @@
+alter table watcher.operations
+    add column if not exists status_id integer;
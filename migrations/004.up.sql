@@ -0,0 +1,10 @@
+-- Время выбора операции для выполнения.
+-- При смене лидера в очередь возвращаются только операции,
+-- выбранные раньше, чем истекает время выполнения (claimed_at).
+alter table watcher.operations
+    add column if not exists claimed_at timestamp;
+
+update watcher.operations
+set claimed_at = updated_at
+where state = 'running'
+    and claimed_at is null;
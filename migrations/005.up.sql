@@ -0,0 +1,37 @@
+-- Уведомление об изменении статуса вне API сервиса.
+-- Транзакции сервиса устанавливают watcher.origin = 'api'.
+create or replace function watcher.notify_status() returns trigger as $$
+declare
+    status_id integer;
+begin
+    if current_setting('watcher.origin', true) is not distinct from 'api' then
+        return null;
+    end if;
+
+    if tg_op = 'DELETE' then
+        status_id := old.id;
+    else
+        status_id := new.id;
+    end if;
+
+    perform pg_notify('watcher_status', status_id::text);
+    return null;
+end;
+$$ language plpgsql;
+
+drop trigger if exists status_notify_update on watcher.status;
+create trigger status_notify_update
+    after update on watcher.status
+    for each row
+    when (
+        old."X" is distinct from new."X"
+        or old."Y" is distinct from new."Y"
+        or old."Z" is distinct from new."Z"
+    )
+    execute function watcher.notify_status();
+
+drop trigger if exists status_notify_delete on watcher.status;
+create trigger status_notify_delete
+    after delete on watcher.status
+    for each row
+    execute function watcher.notify_status();
@@ -0,0 +1,24 @@
+alter table watcher.operations
+    add column if not exists client_id integer,
+    add column if not exists request_id varchar(100);
+
+create table if not exists watcher.operation_log (
+    id bigserial primary key,
+    operation_id bigint,
+    pod_id varchar(100) not null,
+    code smallint not null,
+    status_id integer,
+    client_id integer,
+    request_id varchar(100),
+    attempt integer not null,
+    result varchar(10) not null,
+    error text,
+    started_at timestamp not null,
+    finished_at timestamp not null
+);
+
+create index if not exists operation_log_started_at_idx
+    on watcher.operation_log (started_at);
+
+create index if not exists operation_log_client_id_idx
+    on watcher.operation_log (client_id, started_at);
@@ -0,0 +1,12 @@
+-- Состояние Watcher'а, общее для всех экземпляров сервиса.
+-- Таблица содержит ровно одну строку.
+create table if not exists watcher.control (
+    id boolean primary key default true,
+    paused bool not null default false,
+    updated_at timestamp not null default timezone('UTC', now()),
+
+    check (id)
+);
+
+insert into watcher.control (id) values (true)
+    on conflict do nothing;
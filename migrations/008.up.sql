@@ -0,0 +1,26 @@
+-- Запланированные изменения статусов.
+-- Разовое расписание задаётся run_at и turn_on,
+-- повторяющееся — cron, duration и timezone.
+create table if not exists watcher.schedules (
+    id serial primary key,
+    status_id integer not null,
+    pods varchar(10)[] not null,
+    run_at timestamp,
+    turn_on bool,
+    cron varchar(100),
+    duration interval,
+    timezone varchar(50),
+    last_on bool,
+    applied_at timestamp,
+    created_at timestamp not null default timezone('UTC', now()),
+
+    foreign key (status_id) references watcher.status (id) on delete cascade,
+
+    check (
+        (run_at is not null and turn_on is not null and cron is null)
+        or (run_at is null and cron is not null and duration is not null and timezone is not null)
+    )
+);
+
+create index if not exists schedules_status_id_idx
+    on watcher.schedules (status_id);
@@ -0,0 +1,58 @@
+-- Время жизни включённых подов.
+-- ttl переопределяет время жизни по типам подов для статуса (0 — без ограничений),
+-- <тип>_since — время включения или продления пода.
+alter table watcher.status
+    add column if not exists ttl interval,
+    add column if not exists "X_since" timestamp,
+    add column if not exists "Y_since" timestamp,
+    add column if not exists "Z_since" timestamp;
+
+update watcher.status
+set (
+    "X_since",
+    "Y_since",
+    "Z_since"
+) = (
+    case when "X" then timezone('UTC', now()) end,
+    case when "Y" then timezone('UTC', now()) end,
+    case when "Z" then timezone('UTC', now()) end
+);
+
+-- Время включения отмечается при любом изменении статуса,
+-- в том числе в обход API.
+create or replace function watcher.status_since() returns trigger as $$
+begin
+    if tg_op = 'INSERT' then
+        new."X_since" := case when new."X" then timezone('UTC', now()) end;
+        new."Y_since" := case when new."Y" then timezone('UTC', now()) end;
+        new."Z_since" := case when new."Z" then timezone('UTC', now()) end;
+        return new;
+    end if;
+
+    if not new."X" then
+        new."X_since" := null;
+    elsif not old."X" then
+        new."X_since" := timezone('UTC', now());
+    end if;
+
+    if not new."Y" then
+        new."Y_since" := null;
+    elsif not old."Y" then
+        new."Y_since" := timezone('UTC', now());
+    end if;
+
+    if not new."Z" then
+        new."Z_since" := null;
+    elsif not old."Z" then
+        new."Z_since" := timezone('UTC', now());
+    end if;
+
+    return new;
+end;
+$$ language plpgsql;
+
+drop trigger if exists status_since on watcher.status;
+create trigger status_since
+    before insert or update on watcher.status
+    for each row
+    execute function watcher.status_since();
@@ -0,0 +1,17 @@
+-- Поды, выключенные для освобождения ресурсов кластера.
+-- Записи сохраняются после удаления клиентов.
+create table if not exists watcher.preemptions (
+    id bigserial primary key,
+    status_id integer not null,
+    client_id integer not null,
+    pods varchar(10)[] not null,
+    priority real not null,
+    preempted_by_status_id integer not null,
+    preempted_by_client_id integer not null,
+    preempted_by_priority real not null,
+    request_id varchar(100),
+    created_at timestamp not null default timezone('UTC', now())
+);
+
+create index if not exists preemptions_created_at_idx
+    on watcher.preemptions (created_at);
@@ -0,0 +1,17 @@
+-- Стратегия перезапуска подов клиента и поколения подов.
+-- При перезапуске surge поколение пода увеличивается, а под нового поколения
+-- создаётся до удаления пода с прежним именем (previous_pod_id).
+alter table watcher.clients
+    add column if not exists restart_strategy varchar(10) not null default 'recreate'
+        check (restart_strategy in ('recreate', 'surge'));
+
+alter table watcher.status
+    add column if not exists "X_gen" integer not null default 0,
+    add column if not exists "Y_gen" integer not null default 0,
+    add column if not exists "Z_gen" integer not null default 0;
+
+alter table watcher.operations
+    add column if not exists previous_pod_id varchar(100);
+
+alter table watcher.operation_log
+    add column if not exists previous_pod_id varchar(100);
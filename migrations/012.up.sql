@@ -0,0 +1,46 @@
+-- Поэтапные выкаты образа и версии.
+-- Операции текущей волны (wave) помечаются идентификатором запроса request_id,
+-- wave_ops — количество зарегистрированных операций волны.
+create table if not exists watcher.rollouts (
+    id bigserial primary key,
+    image varchar(50) not null,
+    version smallint not null,
+    selector_image varchar(50),
+    selector_version smallint,
+    selector_client_ids integer[],
+    batch_size integer not null check (batch_size > 0),
+    pause interval not null default '0',
+    max_failure_rate double precision not null check (max_failure_rate between 0 and 1),
+    state varchar(10) not null default 'running'
+        check (state in ('running', 'completed', 'halted', 'cancelled')),
+    reason text,
+    wave integer not null default 0,
+    wave_ops integer not null default 0,
+    request_id varchar(100),
+    wave_started_at timestamp,
+    next_wave_at timestamp,
+    created_at timestamp not null default timezone('UTC', now()),
+    updated_at timestamp not null default timezone('UTC', now())
+);
+
+-- Одновременно выполняется не более одного выката
+create unique index if not exists rollouts_running_idx
+    on watcher.rollouts ((true))
+    where state = 'running';
+
+-- Клиенты выката, отобранные при его создании.
+-- wave — номер волны, обновившей клиента; null — клиент ещё не обновлён.
+create table if not exists watcher.rollout_clients (
+    rollout_id bigint not null,
+    client_id integer not null,
+    wave integer,
+
+    primary key (rollout_id, client_id),
+    foreign key (rollout_id) references watcher.rollouts (id) on delete cascade
+);
+
+create index if not exists operations_request_id_idx
+    on watcher.operations (request_id);
+
+create index if not exists operation_log_request_id_idx
+    on watcher.operation_log (request_id);
@@ -0,0 +1,211 @@
+package breaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/korikhin/pod-sync/pkg/deployer"
+)
+
+// Состояния размыкателя
+type State string
+
+const (
+	// Вызовы передаются Deployer'у
+	StateClosed State = "closed"
+	// Вызовы отклоняются до истечения cooldown
+	StateOpen State = "open"
+	// Пробный вызов определяет, замкнуть размыкатель или разомкнуть снова
+	StateHalfOpen State = "half-open"
+)
+
+// Stats содержит состояние и счётчики размыкателя.
+type Stats struct {
+	State    State
+	Failures int    // Количество неудачных вызовов подряд
+	Opened   uint64 // Количество размыканий
+	Rejected uint64 // Количество отклонённых вызовов
+}
+
+// Breaker реализует размыкатель цепи (circuit breaker) для Deployer'а.
+//
+// После threshold неудачных вызовов подряд размыкатель размыкается
+// и отклоняет вызовы с ошибкой deployer.ErrUnavailable. По истечении cooldown
+// доступность Deployer'а проверяется вызовом GetPodList: при успехе
+// размыкатель переходит в полуоткрытое состояние и пропускает один пробный
+// вызов, по результату которого замыкается или размыкается снова.
+type Breaker struct {
+	d         deployer.Deployer
+	threshold int
+	cooldown  time.Duration
+
+	// Вызывается при смене состояния, не должна блокироваться
+	onChange func(from, to State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool // Выполняется проверка доступности или пробный вызов
+	opened   uint64
+	rejected uint64
+}
+
+var _ deployer.Deployer = (*Breaker)(nil)
+
+// New оборачивает Deployer размыкателем.
+// onChange может быть nil.
+func New(d deployer.Deployer, threshold int, cooldown time.Duration, onChange func(from, to State)) *Breaker {
+	return &Breaker{
+		d:         d,
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		onChange:  onChange,
+		state:     StateClosed,
+	}
+}
+
+func (b *Breaker) CreatePod(name string) error {
+	const op = "deployer.breaker.CreatePod"
+
+	return b.call(op, func() error {
+		return b.d.CreatePod(name)
+	})
+}
+
+func (b *Breaker) DeletePod(name string) error {
+	const op = "deployer.breaker.DeletePod"
+
+	return b.call(op, func() error {
+		return b.d.DeletePod(name)
+	})
+}
+
+func (b *Breaker) GetPodList() ([]string, error) {
+	const op = "deployer.breaker.GetPodList"
+
+	var pods []string
+	err := b.call(op, func() error {
+		var err error
+		pods, err = b.d.GetPodList()
+		return err
+	})
+	return pods, err
+}
+
+// Stats возвращает состояние и счётчики размыкателя.
+func (b *Breaker) Stats() Stats {
+	if b == nil {
+		return Stats{State: StateClosed}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return Stats{
+		State:    b.state,
+		Failures: b.failures,
+		Opened:   b.opened,
+		Rejected: b.rejected,
+	}
+}
+
+// call выполняет вызов, если его допускает состояние размыкателя.
+func (b *Breaker) call(op string, fn func() error) error {
+	trial, err := b.allow()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = fn()
+	b.done(trial, err == nil)
+	return err
+}
+
+// allow сообщает, может ли быть выполнен вызов.
+// Возвращает true, если вызов является пробным.
+func (b *Breaker) allow() (bool, error) {
+	b.mu.Lock()
+
+	switch b.state {
+	case StateClosed:
+		b.mu.Unlock()
+		return false, nil
+	case StateHalfOpen:
+		if b.probing {
+			b.rejected++
+			b.mu.Unlock()
+			return false, deployer.ErrUnavailable
+		}
+		b.probing = true
+		b.mu.Unlock()
+		return true, nil
+	}
+
+	// Разомкнут: проверка доступности выполняется одним вызовом
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		b.rejected++
+		b.mu.Unlock()
+		return false, deployer.ErrUnavailable
+	}
+	b.probing = true
+	b.mu.Unlock()
+
+	_, err := b.d.GetPodList()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil {
+		b.openedAt = time.Now()
+		b.probing = false
+		b.rejected++
+		return false, deployer.ErrUnavailable
+	}
+
+	// Вызов, инициировавший проверку, становится пробным
+	b.setState(StateHalfOpen)
+	return true, nil
+}
+
+// done учитывает результат вызова.
+func (b *Breaker) done(trial, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.probing = false
+	}
+
+	if ok {
+		b.failures = 0
+		if trial {
+			b.setState(StateClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if trial || (b.state == StateClosed && b.failures >= b.threshold) {
+		b.open()
+	}
+}
+
+// open размыкает размыкатель.
+func (b *Breaker) open() {
+	b.openedAt = time.Now()
+	b.opened++
+	b.setState(StateOpen)
+}
+
+func (b *Breaker) setState(to State) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
@@ -0,0 +1,175 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/korikhin/pod-sync/pkg/deployer"
+)
+
+var errFailed = errors.New("failed")
+
+// fakeDeployer возвращает заданные ошибки и считает вызовы CreatePod.
+type fakeDeployer struct {
+	createErr error
+	listErr   error
+	created   int
+}
+
+func (d *fakeDeployer) CreatePod(string) error {
+	d.created++
+	return d.createErr
+}
+
+func (d *fakeDeployer) DeletePod(string) error {
+	return nil
+}
+
+func (d *fakeDeployer) GetPodList() ([]string, error) {
+	return nil, d.listErr
+}
+
+func TestBreaker(t *testing.T) {
+	// Шаг — вызов CreatePod при заданной доступности Deployer'а
+	type step struct {
+		createErr error
+		listErr   error
+		wantErr   error
+		wantState State
+		called    bool // Вызов передан Deployer'у
+	}
+
+	tests := []struct {
+		name      string
+		threshold int
+		cooldown  time.Duration
+		steps     []step
+		opened    uint64
+		rejected  uint64
+	}{
+		{
+			name:      "opens after threshold",
+			threshold: 2,
+			cooldown:  time.Hour,
+			steps: []step{
+				{createErr: errFailed, wantErr: errFailed, wantState: StateClosed, called: true},
+				{createErr: errFailed, wantErr: errFailed, wantState: StateOpen, called: true},
+			},
+			opened: 1,
+		},
+		{
+			name:      "success resets failures",
+			threshold: 2,
+			cooldown:  time.Hour,
+			steps: []step{
+				{createErr: errFailed, wantErr: errFailed, wantState: StateClosed, called: true},
+				{wantState: StateClosed, called: true},
+				{createErr: errFailed, wantErr: errFailed, wantState: StateClosed, called: true},
+			},
+		},
+		{
+			name:      "rejects during cooldown",
+			threshold: 1,
+			cooldown:  time.Hour,
+			steps: []step{
+				{createErr: errFailed, wantErr: errFailed, wantState: StateOpen, called: true},
+				{wantErr: deployer.ErrUnavailable, wantState: StateOpen},
+				{wantErr: deployer.ErrUnavailable, wantState: StateOpen},
+			},
+			opened:   1,
+			rejected: 2,
+		},
+		{
+			name:      "stays open when probe fails",
+			threshold: 1,
+			steps: []step{
+				{createErr: errFailed, wantErr: errFailed, wantState: StateOpen, called: true},
+				{listErr: errFailed, wantErr: deployer.ErrUnavailable, wantState: StateOpen},
+			},
+			opened:   1,
+			rejected: 1,
+		},
+		{
+			name:      "closes after successful trial",
+			threshold: 1,
+			steps: []step{
+				{createErr: errFailed, wantErr: errFailed, wantState: StateOpen, called: true},
+				{wantState: StateClosed, called: true},
+				{wantState: StateClosed, called: true},
+			},
+			opened: 1,
+		},
+		{
+			name:      "reopens after failed trial",
+			threshold: 3,
+			steps: []step{
+				{createErr: errFailed, wantErr: errFailed, wantState: StateClosed, called: true},
+				{createErr: errFailed, wantErr: errFailed, wantState: StateClosed, called: true},
+				{createErr: errFailed, wantErr: errFailed, wantState: StateOpen, called: true},
+				{createErr: errFailed, wantErr: errFailed, wantState: StateOpen, called: true},
+			},
+			opened: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDeployer{}
+			transitions := 0
+			b := New(d, tt.threshold, tt.cooldown, func(from, to State) {
+				if from == to {
+					t.Errorf("transition to the same state %s", to)
+				}
+				transitions++
+			})
+
+			for i, s := range tt.steps {
+				d.createErr, d.listErr = s.createErr, s.listErr
+				created := d.created
+
+				err := b.CreatePod("X-1")
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d: error = %v, want %v", i, err, s.wantErr)
+				}
+				if got := b.Stats().State; got != s.wantState {
+					t.Fatalf("step %d: state = %s, want %s", i, got, s.wantState)
+				}
+				if called := d.created > created; called != s.called {
+					t.Fatalf("step %d: called = %v, want %v", i, called, s.called)
+				}
+			}
+
+			stats := b.Stats()
+			if stats.Opened != tt.opened || stats.Rejected != tt.rejected {
+				t.Errorf("opened = %d, rejected = %d, want %d, %d", stats.Opened, stats.Rejected, tt.opened, tt.rejected)
+			}
+			if tt.opened > 0 && transitions == 0 {
+				t.Error("state changes are not reported")
+			}
+		})
+	}
+}
+
+func TestBreakerSingleTrial(t *testing.T) {
+	d := &fakeDeployer{createErr: errFailed}
+	b := New(d, 1, 0, nil)
+	_ = b.CreatePod("X-1")
+
+	// Пока выполняется пробный вызов, остальные вызовы отклоняются
+	trial, err := b.allow()
+	if !trial || err != nil {
+		t.Fatalf("allow() = %v, %v, want trial", trial, err)
+	}
+	if b.Stats().State != StateHalfOpen {
+		t.Fatalf("state = %s, want %s", b.Stats().State, StateHalfOpen)
+	}
+	if _, err := b.allow(); !errors.Is(err, deployer.ErrUnavailable) {
+		t.Fatalf("second allow() error = %v, want %v", err, deployer.ErrUnavailable)
+	}
+
+	b.done(true, true)
+	if b.Stats().State != StateClosed {
+		t.Errorf("state = %s, want %s", b.Stats().State, StateClosed)
+	}
+}
@@ -1,5 +1,11 @@
 package deployer
 
+import "errors"
+
+// ErrUnavailable возвращается, если вызов не выполнялся, так как Deployer
+// недоступен. Операция может быть повторена без учёта попытки.
+var ErrUnavailable = errors.New("deployer is unavailable")
+
 // go:generate mockery --name Deployer
 type Deployer interface {
 	CreatePod(name string) error